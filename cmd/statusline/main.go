@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/howie/claude-code-omystatusline/pkg/agents"
 	"github.com/howie/claude-code-omystatusline/pkg/apilimits"
@@ -16,6 +17,7 @@ import (
 	"github.com/howie/claude-code-omystatusline/pkg/context"
 	"github.com/howie/claude-code-omystatusline/pkg/git"
 	"github.com/howie/claude-code-omystatusline/pkg/gitstatus"
+	"github.com/howie/claude-code-omystatusline/pkg/pullrequest"
 	"github.com/howie/claude-code-omystatusline/pkg/session"
 	"github.com/howie/claude-code-omystatusline/pkg/speed"
 	"github.com/howie/claude-code-omystatusline/pkg/statusline"
@@ -38,7 +40,19 @@ const (
 	contextWindow200K = 200_000
 )
 
+// jsonOutput 為 --json 模式輸出的結構化資料
+type jsonOutput struct {
+	SessionID  string                     `json:"session_id"`
+	Model      string                     `json:"model"`
+	ToolUsage  map[string]*tools.ToolStat `json:"tool_usage,omitempty"`
+	MCPServers []tools.MCPServerUsage     `json:"mcp_servers,omitempty"`
+	Latency    *speed.LatencyInfo         `json:"latency,omitempty"`
+}
+
 func main() {
+	// --json：輸出結構化資料而非渲染後的 status line
+	jsonMode := hasFlag(os.Args[1:], "--json")
+
 	var input statusline.Input
 	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to decode input: %v\n", err)
@@ -48,8 +62,42 @@ func main() {
 	// Phase 1: 載入配置（同步，快速本地檔案讀取）
 	cfg := config.Load()
 
+	// 設定檔自訂的工具目標模板（如 MCP 或自製工具）
+	for name, tmpl := range cfg.ToolTargets {
+		tools.RegisterExtractor(name, tools.TemplateExtractor(tmpl))
+	}
+
+	// 設定檔自訂的模型輸入價格（計算快取節省金額）
+	if len(cfg.ModelPrices) > 0 {
+		cache.SetPrices(cfg.ModelPrices)
+	}
+
+	// 設定檔覆寫的任務工具名稱（新版 Claude Code 的增量任務工具可能更名）
+	if names := cfg.TodoTools.Snapshot; len(names) > 0 {
+		todo.Tools.Snapshot = names
+	}
+	if names := cfg.TodoTools.Create; len(names) > 0 {
+		todo.Tools.Create = names
+	}
+	if names := cfg.TodoTools.Update; len(names) > 0 {
+		todo.Tools.Update = names
+	}
+
 	// 偵測終端渲染能力
 	context.RenderMode = terminal.Detect()
+	todo.RenderMode = context.RenderMode
+	statusline.HyperlinksEnabled = resolveHyperlinks(cfg.Hyperlinks, terminal.SupportsHyperlinks)
+	gitstatus.StagedCounts = cfg.GitCounts == "staged"
+
+	// 偵測終端背景明暗並切換配色（goroutine 產生的段落已含顏色，需在啟動前完成）。
+	// --json 模式不輸出顏色，不必偵測。
+	var background terminal.BackgroundResolution
+	if !jsonMode {
+		background = terminal.ResolveBackground(cfg.Background)
+		if background.Background == terminal.BackgroundLight {
+			statusline.UsePalette(statusline.LightPalette)
+		}
+	}
 
 	// 取得分隔符設定
 	sep := cfg.GetSeparator()
@@ -78,15 +126,13 @@ func main() {
 	maxTokens, maxTokensSource := resolveMaxTokens(effectiveModelID, input.Model.ID, os.Getenv("STATUSLINE_MAX_TOKENS"))
 
 	// Phase 3: 並行處理所有資料收集
-	results := make(chan statusline.Result, 14)
-	var wg sync.WaitGroup
+	// 每個啟用的區段一個 producer，各回傳一個結果
+	var producers []func() statusline.Result
 
 	// --- Transcript-based goroutines ---
 
 	if cfg.Sections.Context {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			// 優先使用 input.ContextWindow（Claude Code 直接提供，worktree session 也有效）。
 			// Worktree session 的 transcript_path 指向只含 metadata 的小檔案，
 			// 導致 transcript 解析誤判為 NoUsageData（📡）。ContextWindow 則不受此影響。
@@ -94,171 +140,183 @@ func main() {
 			// 不是「無法取得資料」，與 metadata-only transcript 情境語意不同。
 			if hasContextWindow {
 				tokens := contextTokensFromUsage(input.ContextWindow.CurrentUsage)
-				results <- statusline.Result{Type: "context", Data: context.BuildFromTokens(tokens, maxTokens)}
-				return
+				return statusline.Result{Type: "context", Data: context.BuildFromTokens(tokens, maxTokens)}
 			}
 			// Fallback：較舊的 Claude Code 版本沒有 context_window 欄位，從 transcript 解析。
 			// lines == nil && err != nil → 實際讀取失敗，不顯示 context 段落。
 			// lines == nil && err == nil → 空 transcript_path（新 session 尚無資料），
 			// 仍傳入 nil 給 AnalyzeDetailedFromLines，顯示 📡 而非完全隱藏段落。
 			if lines == nil && err != nil {
-				results <- statusline.Result{Type: "context", Data: (*context.ContextData)(nil)}
-				return
+				return statusline.Result{Type: "context", Data: (*context.ContextData)(nil)}
 			}
 			ctxData := context.AnalyzeDetailedFromLines(lines, maxTokens)
-			results <- statusline.Result{Type: "context", Data: ctxData}
-		}()
+			return statusline.Result{Type: "context", Data: ctxData}
+		})
 	}
 
 	if cfg.Sections.UserMessage {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			if lines == nil {
-				results <- statusline.Result{Type: "message", Data: ""}
-				return
+				return statusline.Result{Type: "message", Data: ""}
 			}
 			userMsg := statusline.ExtractUserMessageFromLines(lines, input.SessionID)
-			results <- statusline.Result{Type: "message", Data: userMsg}
-		}()
+			return statusline.Result{Type: "message", Data: userMsg}
+		})
 	}
 
 	if cfg.Sections.Tools {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			if lines == nil {
-				results <- statusline.Result{Type: "tools", Data: ""}
-				return
+				return statusline.Result{Type: "tools", Data: ""}
 			}
-			activeTools := tools.Analyze(lines)
-			results <- statusline.Result{Type: "tools", Data: tools.Format(activeTools)}
-		}()
+			activeTools := tools.Analyze(lines, input.Workspace.CurrentDir)
+			tools.MarkSlow(activeTools, cfg.ToolWarnSeconds)
+			return statusline.Result{Type: "tools", Data: tools.Format(activeTools)}
+		})
+	}
+
+	if cfg.Sections.ToolUsage || jsonMode {
+		producers = append(producers, func() statusline.Result {
+			// 需要整個 session 的統計，不使用只有最後 200 行的 lines，改為增量掃描完整 transcript
+			usage := tools.SessionUsage(input.TranscriptPath, input.SessionID)
+			return statusline.Result{Type: "tool_usage", Data: usage}
+		})
+	}
+
+	if cfg.Sections.Latency || jsonMode {
+		producers = append(producers, func() statusline.Result {
+			// 平均 API 時間需要整個 session 的回應數；TTFT 只看最近的回合
+			responses := speed.SessionResponses(input.TranscriptPath, input.SessionID)
+			latency := speed.Latency(input.Cost.TotalAPIDurationMs, input.Cost.TotalDurationMs,
+				responses, speed.ExtractTurns(lines), cfg.SpeedWindowTurns)
+			return statusline.Result{Type: "latency", Data: latency}
+		})
 	}
 
 	if cfg.Sections.Agents {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			if lines == nil {
-				results <- statusline.Result{Type: "agents", Data: ""}
-				return
+				return statusline.Result{Type: "agents", Data: []agents.AgentInfo(nil)}
 			}
-			activeAgents := agents.Analyze(lines, input.SessionID)
-			results <- statusline.Result{Type: "agents", Data: agents.Format(activeAgents)}
-		}()
+			linger := time.Duration(cfg.AgentLingerSeconds) * time.Second
+			activeAgents := agents.Analyze(lines, input.SessionID, linger)
+			return statusline.Result{Type: "agents", Data: activeAgents}
+		})
 	}
 
 	if cfg.Sections.Todo {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if lines == nil {
-				results <- statusline.Result{Type: "todo", Data: ""}
-				return
+		producers = append(producers, func() statusline.Result {
+			// 增量任務工具的任務可能早已建立，重播整份 transcript；無法讀取時退回最近的行
+			todoInfo := todo.SessionTodos(input.TranscriptPath, input.SessionID)
+			if todoInfo == nil && lines != nil {
+				todoInfo = todo.Analyze(lines)
 			}
-			todoInfo := todo.Analyze(lines)
-			results <- statusline.Result{Type: "todo", Data: todo.Format(todoInfo)}
-		}()
+			return statusline.Result{Type: "todo", Data: todoInfo}
+		})
 	}
 
 	if cfg.Sections.Speed {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if lines == nil || input.SessionID == "" {
-				results <- statusline.Result{Type: "speed", Data: ""}
-				return
+		producers = append(producers, func() statusline.Result {
+			if lines == nil {
+				return statusline.Result{Type: "speed", Data: ""}
 			}
-			speedInfo := speed.Calculate(lines, input.SessionID)
-			results <- statusline.Result{Type: "speed", Data: speed.Format(speedInfo)}
-		}()
+			speedInfo := speed.Calculate(lines, input.SessionID, cfg.SpeedWindowTurns)
+			return statusline.Result{Type: "speed", Data: speed.Format(speedInfo)}
+		})
 	}
 
 	if cfg.Sections.Autocompact {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			if lines == nil {
-				results <- statusline.Result{Type: "autocompact", Data: ""}
-				return
+				return statusline.Result{Type: "autocompact", Data: ""}
 			}
 			acInfo := context.DetectAutocompact(lines)
-			results <- statusline.Result{Type: "autocompact", Data: context.FormatAutocompact(acInfo)}
-		}()
+			return statusline.Result{Type: "autocompact", Data: context.FormatAutocompact(acInfo)}
+		})
 	}
 
 	if cfg.Sections.CacheHitRate {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if lines == nil {
-				results <- statusline.Result{Type: "cache", Data: (*cache.CacheInfo)(nil)}
-				return
+		producers = append(producers, func() statusline.Result {
+			// 整個 session 的累計值；無法增量掃描時退回最新一則 usage
+			cacheInfo := cache.Session(input.TranscriptPath, input.SessionID)
+			if cacheInfo == nil && lines != nil {
+				cacheInfo = cache.Calculate(lines)
 			}
-			cacheInfo := cache.Calculate(lines)
-			results <- statusline.Result{Type: "cache", Data: cacheInfo}
-		}()
+			return statusline.Result{Type: "cache", Data: cacheInfo}
+		})
+	}
+
+	if cfg.Sections.CacheExpiry {
+		producers = append(producers, func() statusline.Result {
+			return statusline.Result{Type: "cache_expiry", Data: cache.CalculateExpiry(lines, time.Now())}
+		})
 	}
 
 	if cfg.Sections.SessionName {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			// 優先使用 input.SessionName（Claude Code v2.1.x+ 直接提供，零 transcript 掃描，
 			// worktree 的 metadata-only transcript 也有效）。缺此欄位時 fallback 到掃描
 			// transcript（較舊版本 Claude Code）。
 			if input.SessionName != "" {
-				results <- statusline.Result{Type: "session_name", Data: input.SessionName}
-				return
+				return statusline.Result{Type: "session_name", Data: input.SessionName}
 			}
 			if lines == nil {
-				results <- statusline.Result{Type: "session_name", Data: ""}
-				return
+				return statusline.Result{Type: "session_name", Data: ""}
 			}
 			name := statusline.ExtractSessionName(lines, input.SessionID)
-			results <- statusline.Result{Type: "session_name", Data: name}
-		}()
+			return statusline.Result{Type: "session_name", Data: name}
+		})
 	}
 
 	// --- External goroutines ---
 
 	if cfg.Sections.Git {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if input.Worktree.Branch != "" {
-				branch := git.FormatWorktreeBranch(input.Worktree.Name, input.Worktree.Branch)
-				results <- statusline.Result{Type: "git", Data: branch}
-				return
+		producers = append(producers, func() statusline.Result {
+			dir := input.Workspace.CurrentDir
+			name := input.Worktree.Branch
+			var branch string
+			if name != "" {
+				branch = git.FormatWorktreeBranch(input.Worktree.Name, name)
+			} else {
+				branch = git.GetBranch(dir)
 			}
-			branch := git.GetBranch(input.Workspace.CurrentDir)
-			results <- statusline.Result{Type: "git", Data: branch}
-		}()
+			if statusline.HyperlinksEnabled && branch != "" {
+				branch = linkBranch(branch, dir, name, cfg.BranchURL)
+			}
+			return statusline.Result{Type: "git", Data: branch}
+		})
 	}
 
 	if cfg.Sections.GitStatus {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			gitStatusInfo := gitstatus.Get(input.Workspace.CurrentDir)
-			results <- statusline.Result{Type: "git_status", Data: gitstatus.Format(gitStatusInfo)}
-		}()
+		producers = append(producers, func() statusline.Result {
+			details := gitstatus.Details{
+				Stash:      cfg.Sections.GitStash,
+				DiffStat:   cfg.Sections.GitDiffStat,
+				LastCommit: cfg.Sections.GitLastCommit,
+			}
+			gitStatusInfo := gitstatus.Get(input.Workspace.CurrentDir, details)
+			return statusline.Result{Type: "git_status", Data: gitstatus.Format(gitStatusInfo) + gitstatus.FormatDetails(gitStatusInfo, details)}
+		})
+	}
+
+	if cfg.Sections.PR {
+		producers = append(producers, func() statusline.Result {
+			// transcript 的 pr-link 優先，其次是 gh/glab checkout 寫入的分支 upstream 設定。
+			// lines 為 nil 時仍可從分支設定推導。
+			prInfo := pullrequest.Find(lines, input.Workspace.CurrentDir)
+			return statusline.Result{Type: "pr", Data: prInfo}
+		})
 	}
 
 	if cfg.Sections.Session {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			totalHours := session.CalculateTotalHours(input.SessionID)
-			results <- statusline.Result{Type: "hours", Data: totalHours}
-		}()
+			return statusline.Result{Type: "hours", Data: totalHours}
+		})
 	}
 
 	if cfg.Sections.APILimits {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+		producers = append(producers, func() statusline.Result {
 			// 優先使用 input.RateLimits（Claude Code v2.1.x+ 直接提供，零網路延遲、
 			// 不限驗證方式）。ResetsAt > 0 代表此 feature 存在；否則 fallback 到
 			// OAuth usage API（較舊版本 Claude Code 不提供 rate_limits）。
@@ -272,17 +330,26 @@ func main() {
 			} else {
 				limitsInfo = apilimits.Fetch()
 			}
-			results <- statusline.Result{Type: "api_limits", Data: apilimits.Format(limitsInfo)}
-		}()
+			return statusline.Result{Type: "api_limits", Data: apilimits.Format(limitsInfo)}
+		})
 	}
 
-	if cfg.Sections.ConfigInfo {
+	if cfg.Sections.ConfigInfo || jsonMode {
+		producers = append(producers, func() statusline.Result {
+			counts := statusline.CountConfigFiles(input.Workspace.CurrentDir)
+			return statusline.Result{Type: "config_info", Data: counts}
+		})
+	}
+
+	// 緩衝區與 producer 數量相同，每個 goroutine 送出結果後即可結束，不需等待收集端
+	results := make(chan statusline.Result, len(producers))
+	var wg sync.WaitGroup
+	for _, produce := range producers {
 		wg.Add(1)
-		go func() {
+		go func(produce func() statusline.Result) {
 			defer wg.Done()
-			counts := statusline.CountConfigFiles(input.Workspace.CurrentDir)
-			results <- statusline.Result{Type: "config_info", Data: statusline.FormatConfigCounts(counts)}
-		}()
+			results <- produce()
+		}(produce)
 	}
 
 	// 等待所有 goroutines 完成
@@ -295,6 +362,7 @@ func main() {
 	var (
 		gitBranch      string
 		gitStatusStr   string
+		prInfo         *pullrequest.PRInfo
 		totalHours     string
 		contextBar     string
 		contextInfo    string
@@ -302,15 +370,19 @@ func main() {
 		contextHasData bool
 		userMessage    string
 		toolsStr       string
-		agentsStr      string
+		toolUsage      *tools.UsageStats
+		activeAgents   []agents.AgentInfo
 		todoStr        string
+		todoInfo       *todo.TodoInfo
 		speedStr       string
+		latency        *speed.LatencyInfo
 		autocompact    string
 		cacheStr       string
 		cacheRate      int
+		cacheExpiry    *cache.ExpiryInfo
 		sessionName    string
 		apiLimits      string
-		configInfo     string
+		configCounts   *statusline.ConfigCounts
 	)
 
 	for result := range results {
@@ -319,6 +391,8 @@ func main() {
 			gitBranch = result.Data.(string)
 		case "git_status":
 			gitStatusStr = result.Data.(string)
+		case "pr":
+			prInfo, _ = result.Data.(*pullrequest.PRInfo)
 		case "hours":
 			totalHours = result.Data.(string)
 		case "context":
@@ -339,12 +413,17 @@ func main() {
 			userMessage = result.Data.(string)
 		case "tools":
 			toolsStr = result.Data.(string)
+		case "tool_usage":
+			toolUsage, _ = result.Data.(*tools.UsageStats)
 		case "agents":
-			agentsStr = result.Data.(string)
+			activeAgents, _ = result.Data.([]agents.AgentInfo)
 		case "todo":
-			todoStr = result.Data.(string)
+			todoInfo = result.Data.(*todo.TodoInfo)
+			todoStr = todo.Format(todoInfo)
 		case "speed":
 			speedStr = result.Data.(string)
+		case "latency":
+			latency, _ = result.Data.(*speed.LatencyInfo)
 		case "autocompact":
 			autocompact = result.Data.(string)
 		case "cache":
@@ -353,21 +432,44 @@ func main() {
 				cacheStr = cache.Format(cacheInfo)
 				cacheRate = cacheInfo.HitRate
 			}
+		case "cache_expiry":
+			cacheExpiry, _ = result.Data.(*cache.ExpiryInfo)
 		case "session_name":
 			sessionName = result.Data.(string)
 		case "api_limits":
 			apiLimits = result.Data.(string)
 		case "config_info":
-			configInfo = result.Data.(string)
+			configCounts, _ = result.Data.(*statusline.ConfigCounts)
 		}
 	}
 
 	// 更新 session（同步操作）
 	session.Update(input.SessionID)
 
+	if jsonMode {
+		out := jsonOutput{SessionID: input.SessionID, Model: input.Model.ID}
+		if toolUsage != nil {
+			out.ToolUsage = toolUsage.Tools
+		}
+		var configured []string
+		if configCounts != nil {
+			configured = configCounts.MCPServerNames
+		}
+		out.MCPServers = tools.MCPUsage(toolUsage, configured)
+		out.Latency = latency
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintf(os.Stderr, "statusline: failed to encode JSON output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Phase 5: 格式化輸出
 	modelDisplay := statusline.FormatModel(input.Model.DisplayName)
-	projectName := filepath.Base(input.Workspace.CurrentDir)
+	projectName := statusline.Hyperlink(statusline.FileURL(input.Workspace.CurrentDir, 0),
+		filepath.Base(input.Workspace.CurrentDir))
 
 	// Cost 顯示（顏色分級：<$5 預設，≥$5 黃色，≥$10 紅色）
 	costDisplay := ""
@@ -385,6 +487,13 @@ func main() {
 		gitDisplay += statusline.FormatGitStatusDisplay(gitStatusStr)
 	}
 
+	// PR 編號（有網址時以 OSC 8 超連結顯示）
+	prDisplay := ""
+	if prInfo != nil {
+		prDisplay = statusline.FormatPRDisplay(pullrequest.Format(prInfo),
+			pullrequest.PRURL(prInfo, input.Workspace.CurrentDir, cfg.PRURL))
+	}
+
 	// Speed 附加在 context 後
 	speedDisplay := statusline.FormatSpeedDisplay(speedStr)
 
@@ -394,11 +503,34 @@ func main() {
 	// Cache hit rate
 	cacheDisplay := statusline.FormatCacheDisplay(cacheStr, cacheRate)
 
+	// Prompt cache 過期倒數（接近過期或已過期時為紅色）
+	cacheExpiryDisplay := ""
+	if cacheExpiry != nil {
+		cacheExpiryDisplay = statusline.FormatCacheExpiryDisplay(cache.FormatExpiry(cacheExpiry),
+			cacheExpiry.Remaining < cache.ExpiryWarning)
+	}
+
+	// Session 工具使用統計（只在 tool_usage 區段開啟時顯示，--json 模式也會收集）
+	toolUsageDisplay := ""
+	if cfg.Sections.ToolUsage {
+		toolUsageDisplay = statusline.FormatToolUsageDisplay(tools.FormatUsage(toolUsage))
+	}
+
+	// API 延遲（只在 latency 區段開啟時顯示，--json 模式也會收集）
+	latencyDisplay := ""
+	if cfg.Sections.Latency {
+		latencyDisplay = statusline.FormatLatencyDisplay(speed.FormatLatency(latency))
+	}
+
 	// Session name
 	sessionNameDisplay := statusline.FormatSessionNameDisplay(sessionName)
 
 	// Config info（加前導分隔符，與其他段落視覺一致）
 	configInfoDisplay := ""
+	configInfo := ""
+	if cfg.Sections.ConfigInfo {
+		configInfo = statusline.FormatConfigCounts(configCounts)
+	}
 	if configInfo != "" {
 		configInfoDisplay = fmt.Sprintf("%s%s%s%s", sep.Divider, statusline.ColorDim, configInfo, statusline.ColorReset)
 	}
@@ -410,7 +542,8 @@ func main() {
 	}
 
 	// 偵測終端寬度
-	termWidth := terminal.Width()
+	width := terminal.ResolveWidth(cfg.TerminalWidth)
+	termWidth := width.Width
 
 	// Session time 與前導分隔符合併為一個段落，避免移除 session 後留下孤立分隔符
 	sessionWithDivider := ""
@@ -425,18 +558,24 @@ func main() {
 		{Content: fmt.Sprintf("%s[%s] 📂 %s", statusline.ColorReset, modelDisplay, projectName), Priority: 1},
 		{Content: sessionNameDisplay, Priority: 10},
 		{Content: gitDisplay, Priority: 3},
+		{Content: prDisplay, Priority: 13},
 		{Content: contextBar, Priority: 4},
 		{Content: contextInfo, Priority: 2},
 		{Content: speedDisplay, Priority: 7},
 		{Content: autocompactDisplay, Priority: 8},
 		{Content: cacheDisplay, Priority: 12},
+		{Content: cacheExpiryDisplay, Priority: 12},
 		{Content: linesDisplay, Priority: 9},
 		{Content: sessionWithDivider, Priority: 5},
 		{Content: costDisplay, Priority: 6},
 		{Content: configInfoDisplay, Priority: 11},
+		{Content: toolUsageDisplay, Priority: 14},
+		{Content: latencyDisplay, Priority: 15},
 		{Content: statusline.ColorReset, Priority: 0},
 	}
 	if os.Getenv("STATUSLINE_DEBUG") == "1" {
+		fmt.Fprintf(os.Stderr, "[debug] terminal %s\n", width)
+		fmt.Fprintf(os.Stderr, "[debug] terminal %s\n", background)
 		fmt.Fprintf(os.Stderr, "[debug] termWidth=%d overflowMode=%q tokens=%d hasData=%v effectiveModel=%q maxTokens=%d maxTokensSource=%q\n",
 			termWidth, cfg.OverflowMode, contextTokens, contextHasData, effectiveModelID, maxTokens, maxTokensSource)
 		total := 0
@@ -455,26 +594,36 @@ func main() {
 
 	// Line 2: 工具行（expanded 模式）
 	if cfg.DisplayMode == "expanded" {
+		// 工具目標可能是超連結，過長時以可辨識 escape sequence 的方式截斷
 		if toolsLine := statusline.FormatToolsLine(toolsStr); toolsLine != "" {
-			fmt.Println(toolsLine)
+			fmt.Println(statusline.TruncateVisible(toolsLine, termWidth))
 		}
 
-		// Line 3: 代理行
-		if agentsLine := statusline.FormatAgentsLine(agentsStr); agentsLine != "" {
-			fmt.Println(agentsLine)
+		// Line 3: 代理樹（每個代理一行，子代理縮排）
+		for _, agentLine := range agents.FormatTree(activeAgents) {
+			fmt.Println(statusline.FormatAgentsLine(agentLine))
 		}
 
-		// Line 4: Todo + API Limits
-		if todoLine := statusline.FormatTodoLine(todoStr, apiLimits); todoLine != "" {
+		// Line 4: Todo + API Limits（full 模式下接著列出完整清單）
+		todoRows := []string{todoStr}
+		if cfg.TodoMode == "full" {
+			if rows := todo.FormatFull(todoInfo, cfg.TodoMaxRows); len(rows) > 0 {
+				todoRows = rows
+			}
+		}
+		if todoLine := statusline.FormatTodoLine(todoRows[0], apiLimits); todoLine != "" {
 			fmt.Println(todoLine)
 		}
+		for _, row := range todoRows[1:] {
+			fmt.Println(statusline.FormatTodoLine(row, ""))
+		}
 	} else {
 		// Compact 模式：壓縮到一行
 		var compactParts []string
 		if toolsStr != "" {
 			compactParts = append(compactParts, toolsStr)
 		}
-		if agentsStr != "" {
+		if agentsStr := agents.Format(activeAgents); agentsStr != "" {
 			compactParts = append(compactParts, agentsStr)
 		}
 		if todoStr != "" {
@@ -497,6 +646,39 @@ func main() {
 	}
 }
 
+// resolveHyperlinks 依 hyperlinks 設定決定是否輸出 OSC 8 超連結；
+// "auto"（或空字串）時呼叫 detect 偵測終端，未知值輸出警告並視為 "auto"。
+func resolveHyperlinks(mode string, detect func() bool) bool {
+	switch mode {
+	case "on":
+		return true
+	case "off":
+		return false
+	case "auto", "":
+		return detect()
+	default:
+		fmt.Fprintf(os.Stderr, "statusline: unknown hyperlinks %q, falling back to \"auto\"\n", mode)
+		return detect()
+	}
+}
+
+// linkBranch 將分支顯示（如 " 🌿 main"）的文字部分包成超連結：
+// 優先連到分支網頁（branch_url 樣板或 <倉庫網頁>/tree/<branch>），沒有 remote 時連到本機倉庫根目錄。
+// name 為已知的分支名稱；空字串時從倉庫讀取。
+func linkBranch(display, dir, name, tmpl string) string {
+	if name == "" {
+		if repo := git.Open(dir); repo != nil {
+			name, _ = repo.Branch()
+		}
+	}
+	url := pullrequest.BranchURL(dir, name, tmpl)
+	if url == "" {
+		url = statusline.FileURL(git.RepoRoot(dir), 0)
+	}
+	text := strings.TrimPrefix(display, " ")
+	return display[:len(display)-len(text)] + statusline.Hyperlink(url, text)
+}
+
 // formatSegments applies the configured overflow mode to segments.
 // "truncate" calls TruncateLine; "wrap" and any unknown value call WrapLine.
 // Unknown values emit a warning to stderr and fall back to "wrap".
@@ -619,6 +801,16 @@ func contextTokensFromUsage(u statusline.ContextUsage) int {
 	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
 }
 
+// hasFlag 判斷命令列參數中是否有指定旗標
+func hasFlag(args []string, flag string) bool {
+	for _, a := range args {
+		if a == flag {
+			return true
+		}
+	}
+	return false
+}
+
 func joinWithSep(parts []string, sep string) string {
 	var nonEmpty []string
 	for _, p := range parts {
@@ -48,6 +48,40 @@ func TestFormatSegments(t *testing.T) {
 	})
 }
 
+func TestResolveHyperlinks(t *testing.T) {
+	detected := func() bool { return true }
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		{"on", true},
+		{"off", false},
+		{"auto", true},
+		{"", true},
+		{"bogus", true},
+	}
+	for _, tt := range tests {
+		if got := resolveHyperlinks(tt.mode, detected); got != tt.want {
+			t.Errorf("resolveHyperlinks(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+	if resolveHyperlinks("auto", func() bool { return false }) {
+		t.Error("auto should follow terminal detection")
+	}
+}
+
+func TestLinkBranch(t *testing.T) {
+	dir := t.TempDir()
+	got := linkBranch(" 🌿 main", dir, "main", "https://git.example.com/{branch}")
+	want := " " + statusline.Hyperlink("https://git.example.com/main", "🌿 main")
+	if got != want {
+		t.Errorf("linkBranch = %q, want %q", got, want)
+	}
+	if w := statusline.VisibleWidth(got); w != statusline.VisibleWidth(" 🌿 main") {
+		t.Errorf("hyperlink should not change visible width, got %d", w)
+	}
+}
+
 // TestContextWindowMaxTokens 驗證 maxTokens 永遠使用 contextWindowForModel（非 ContextWindowSize）。
 // ContextWindowSize from Claude Code is the current token count, not the model's max capacity.
 // Using it as the denominator causes percentage ≈ 100% for all sessions (the bug this fixes).
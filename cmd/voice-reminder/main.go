@@ -25,6 +25,9 @@ func main() {
 		case "--stats":
 			printStats()
 			return
+		case voicereminder.QueueWorkerFlag:
+			runQueueWorker()
+			return
 		}
 	}
 
@@ -72,10 +75,30 @@ func main() {
 	message := voicereminder.SelectMessage(config, &input)
 	logger.Log("選擇的語音訊息: %s", message)
 
-	// 7. 播放語音
-	if config.SoundEffects.Enabled {
+	// 7. 播放語音：預設放入佇列由背景 worker 依序播放，hook 立即返回
+	if config.SoundEffects.Enabled && config.Queue.Async() {
+		queued, err := voicereminder.Enqueue(voicereminder.Utterance{
+			Text:     message,
+			Language: config.Language,
+			Rate:     config.Speed,
+			Event:    input.HookEventName,
+		}, config.Queue.Window())
+		switch {
+		case err != nil:
+			logger.Log("加入語音佇列失敗: %v", err)
+		case !queued:
+			logger.Log("相同訊息已在佇列中或剛播放過，略過")
+		default:
+			if err := voicereminder.StartWorker(); err != nil {
+				logger.Log("啟動語音 worker 失敗: %v", err)
+			} else {
+				logger.Log("已加入語音佇列")
+			}
+		}
+	} else if config.SoundEffects.Enabled {
 		logger.Log("開始播放語音...")
-		if err := voicereminder.SpeakWithLogger(message, config.Speed, config.Language, logger); err != nil {
+		if err := voicereminder.SpeakWith(config.Backends(logger), message,
+			voicereminder.SpeakOptions{Language: config.Language, Rate: config.Speed}, logger); err != nil {
 			logger.Log("語音播放錯誤: %v", err)
 		} else {
 			logger.Log("語音播放成功")
@@ -96,6 +119,24 @@ func main() {
 	logger.Log("========== 處理完成 ==========\n")
 }
 
+// runQueueWorker 背景 worker：依序播放佇列中的語音直到清空
+func runQueueWorker() {
+	config, err := voicereminder.LoadConfig()
+	if err != nil {
+		return
+	}
+	logger := voicereminder.NewLogger(config.DebugMode || os.Getenv("VOICE_REMINDER_DEBUG") == "true")
+	defer logger.Close()
+
+	chain := config.Backends(logger)
+	speak := func(u voicereminder.Utterance) error {
+		return voicereminder.SpeakWith(chain, u.Text, voicereminder.SpeakOptions{Language: u.Language, Rate: u.Rate}, logger)
+	}
+	if err := voicereminder.RunQueue(speak, config.Queue.Window(), logger); err != nil {
+		logger.Log("語音佇列錯誤: %v", err)
+	}
+}
+
 func printHelp() {
 	fmt.Println("voice-reminder - Claude Code Voice Notification System")
 	fmt.Printf("Version: %s\n\n", version)
@@ -112,6 +153,8 @@ func printHelp() {
 	fmt.Println("OPTIONS:")
 	fmt.Println("  -h, --help     Show this help message")
 	fmt.Println("  -v, --version  Show version information")
+	fmt.Println("  --stats        Show usage statistics")
+	fmt.Println("  --speak-queue  Play queued messages (started automatically by the hook)")
 	fmt.Println()
 	fmt.Println("CONFIGURATION:")
 	fmt.Println("  Config file:  ~/.claude/voice-reminder-config.json")
@@ -120,10 +163,13 @@ func printHelp() {
 	fmt.Println("  Stats file:   ~/.claude/voice-reminder-stats.json")
 	fmt.Println()
 	fmt.Println("FEATURES:")
+	fmt.Println("  - Non-blocking hook: messages are queued and spoken one at a time")
+	fmt.Println("  - Duplicate messages within a few seconds are spoken only once")
 	fmt.Println("  - 10-second timeout protection for voice playback")
 	fmt.Println("  - Automatic retry on failure (1 retry)")
 	fmt.Println("  - Fallback to system sounds when voice fails")
 	fmt.Println("  - Multi-language support (English, Chinese)")
+	fmt.Println("  - TTS backends: say, espeak-ng, espeak, spd-say, festival, piper, custom command")
 	fmt.Println("  - Debug logging for troubleshooting")
 	fmt.Println()
 	fmt.Println("SLASH COMMANDS:")
@@ -2,189 +2,505 @@ package agents
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
-// AgentInfo 代表一個正在執行的子代理
+// AgentInfo 代表一個子代理（執行中或近期完成）
 type AgentInfo struct {
-	Type        string // e.g. "Agent", "Explore"
-	Model       string // e.g. "Sonnet", "Haiku"
-	Description string
-	StartTime   time.Time
-	ElapsedSec  int
-	Completed   bool
+	ID           string // 啟動此代理的 tool_use ID
+	ParentID     string // 上層代理的 tool_use ID；由主對話啟動時為空
+	Depth        int    // 在代理樹中的深度（主對話啟動者為 0）
+	Type         string // e.g. "Agent", "Explore"
+	Model        string // e.g. "Sonnet", "Haiku"
+	Description  string
+	StartTime    time.Time
+	EndTime      time.Time // tool_result（或 SubagentStop）所在行的時間戳；未完成或無時間戳時為零值
+	ElapsedSec   int       // 執行中為已耗時間，完成後為總耗時
+	Completed    bool
+	Failed       bool // tool_result 帶 is_error: true
+	InputTokens  int  // sidechain usage 累計：input + cache creation + cache read
+	OutputTokens int  // sidechain usage 累計：output
+}
+
+// TotalTokens 代理累計消耗的 token 數
+func (a AgentInfo) TotalTokens() int {
+	return a.InputTokens + a.OutputTokens
 }
 
 // MaxAgents 最多顯示的代理數量
 const MaxAgents = 3
 
-// Analyze 從 transcript 行中找出正在執行的子代理
-func Analyze(lines []transcript.Line, sessionID string) []AgentInfo {
-	// 追蹤 agent 生命週期
-	activeAgents := make(map[string]*AgentInfo) // agentId -> AgentInfo
-	var agentOrder []string
+// MaxCompletedAgents 最多顯示的近期完成代理數量
+const MaxCompletedAgents = 2
 
-	now := time.Now()
+// tracker 從 transcript 重建代理樹的狀態
+type tracker struct {
+	agents    map[string]*AgentInfo // toolUseId -> AgentInfo
+	order     []string              // 依啟動順序
+	byAgentID map[string]string     // sidechain agentId -> toolUseId
+	byUUID    map[string]string     // sidechain 行 uuid -> toolUseId，沿 parentUuid 鏈歸屬
+	bound     map[string]bool       // 已對應到 sidechain 的 toolUseId
+	seenMsg   map[string]bool       // toolUseId + message.id，避免同一則訊息拆成多行時重複計算 usage
+	now       time.Time
+}
+
+// Analyze 從 transcript 行中找出正在執行的子代理，依代理樹的深度優先順序回傳，
+// 之後附上在 linger 時間內完成的代理（Completed 為 true，最多 MaxCompletedAgents 個）；linger <= 0 時不保留。
+// sidechain 行（以及 progress 行內嵌的訊息）依 parentToolUseID、agentId 或 parentUuid 鏈
+// 歸屬到啟動它的 Agent tool_use，用來累計各代理的 token 與模型，並找出巢狀的 Agent 呼叫。
+func Analyze(lines []transcript.Line, sessionID string, linger time.Duration) []AgentInfo {
+	t := &tracker{
+		agents:    make(map[string]*AgentInfo),
+		byAgentID: make(map[string]string),
+		byUUID:    make(map[string]string),
+		bound:     make(map[string]bool),
+		seenMsg:   make(map[string]bool),
+		now:       time.Now(),
+	}
 
 	for _, l := range lines {
 		if l.Parsed == nil {
 			continue
 		}
+		t.process(l.Parsed)
+	}
 
-		// 檢查 agent 相關事件
-		agentID, _ := l.Parsed["agentId"].(string)
-		agentType, _ := l.Parsed["agent_type"].(string)
-		hookEvent, _ := l.Parsed["hook_event_name"].(string)
-		msgType, _ := l.Parsed["type"].(string)
-
-		// 檢查 tool_use 中的 Agent 工具呼叫
-		if msg, ok := l.Parsed["message"].(map[string]interface{}); ok {
-			role, _ := msg["role"].(string)
-			if role == "assistant" {
-				if content, ok := msg["content"].([]interface{}); ok {
-					for _, block := range content {
-						blockMap, ok := block.(map[string]interface{})
-						if !ok {
-							continue
-						}
-						blockType, _ := blockMap["type"].(string)
-						name, _ := blockMap["name"].(string)
-						toolID, _ := blockMap["id"].(string)
-
-						if blockType == "tool_use" && name == "Agent" {
-							input, _ := blockMap["input"].(map[string]interface{})
-							desc, _ := input["description"].(string)
-							subType, _ := input["subagent_type"].(string)
-							if subType == "" {
-								subType = "Agent"
-							}
-
-							// 提取時間戳
-							startTime := extractTimestamp(l.Parsed, now)
-
-							activeAgents[toolID] = &AgentInfo{
-								Type:        subType,
-								Description: truncateDesc(desc, 40),
-								StartTime:   startTime,
-							}
-							agentOrder = append(agentOrder, toolID)
-						}
-					}
+	return append(t.running(), t.recent(linger)...)
+}
+
+// process 處理單一 transcript 行
+func (t *tracker) process(parsed map[string]interface{}) {
+	owner := t.ownerOf(parsed)
+
+	// 檢查 tool_use 中的 Agent 工具呼叫與 tool_result
+	if msg := lineMessage(parsed); msg != nil {
+		role, _ := msg["role"].(string)
+		if role == "assistant" && owner != "" {
+			t.attributeUsage(owner, msg)
+		}
+
+		content, _ := msg["content"].([]interface{})
+		for _, block := range content {
+			blockMap, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			blockType, _ := blockMap["type"].(string)
+
+			if role == "assistant" && blockType == "tool_use" {
+				name, _ := blockMap["name"].(string)
+				toolID, _ := blockMap["id"].(string)
+				if (name == "Agent" || name == "Task") && toolID != "" {
+					t.start(toolID, owner, blockMap, parsed)
 				}
 			}
 
 			// 檢查 tool_result 來標記完成
-			if role == "user" {
-				if content, ok := msg["content"].([]interface{}); ok {
-					for _, block := range content {
-						blockMap, ok := block.(map[string]interface{})
-						if !ok {
-							continue
-						}
-						blockType, _ := blockMap["type"].(string)
-						toolUseID, _ := blockMap["tool_use_id"].(string)
-						if blockType == "tool_result" && toolUseID != "" {
-							if agent, exists := activeAgents[toolUseID]; exists {
-								agent.Completed = true
-							}
+			if role == "user" && blockType == "tool_result" {
+				toolUseID, _ := blockMap["tool_use_id"].(string)
+				if agent, exists := t.agents[toolUseID]; exists {
+					isErr, _ := blockMap["is_error"].(bool)
+					t.complete(agent, parsed, isErr)
+					// toolUseResult.agentId 讓之後的 SubagentStop 事件能對應回此 tool_use
+					if res, ok := parsed["toolUseResult"].(map[string]interface{}); ok {
+						if id, _ := res["agentId"].(string); id != "" {
+							t.byAgentID[id] = toolUseID
 						}
 					}
 				}
 			}
 		}
+	}
 
-		// 處理子代理的事件
-		if agentID != "" && agentType != "" {
-			if hookEvent == "SubagentStop" || msgType == "agent_stop" {
-				// 標記完成
-				for _, a := range activeAgents {
-					if a.Type == agentType && !a.Completed {
-						a.Completed = true
-						break
-					}
-				}
-			}
+	// 處理子代理的停止事件
+	hookEvent, _ := parsed["hook_event_name"].(string)
+	msgType, _ := parsed["type"].(string)
+	if hookEvent == "SubagentStop" || msgType == "agent_stop" {
+		if agent := t.stoppedAgent(parsed); agent != nil {
+			t.complete(agent, parsed, false)
 		}
 	}
+}
 
-	// 收集未完成的 agents，計算耗時
-	var running []AgentInfo
-	for i := len(agentOrder) - 1; i >= 0; i-- {
-		id := agentOrder[i]
-		agent := activeAgents[id]
-		if agent != nil && !agent.Completed {
-			agent.ElapsedSec = int(now.Sub(agent.StartTime).Seconds())
-			if agent.ElapsedSec < 0 {
-				agent.ElapsedSec = 0
-			}
-			running = append(running, *agent)
-			if len(running) >= MaxAgents {
-				break
-			}
+// stoppedAgent 找出停止事件對應的代理。
+// 優先以 agentId 對應（sidechain、progress 行與 toolUseResult 建立的對照）；
+// 無法對應時，只有在恰好一個同類型代理執行中才以類型判斷，避免並行的同類型代理被誤標。
+func (t *tracker) stoppedAgent(parsed map[string]interface{}) *AgentInfo {
+	agentID, _ := parsed["agentId"].(string)
+	if agentID == "" {
+		agentID, _ = parsed["agent_id"].(string)
+	}
+	if owner, ok := t.byAgentID[agentID]; ok && agentID != "" {
+		return t.agents[owner]
+	}
+
+	agentType, _ := parsed["agent_type"].(string)
+	if agentType == "" {
+		return nil
+	}
+	var match *AgentInfo
+	for _, id := range t.order {
+		a := t.agents[id]
+		if a.Type != agentType || a.Completed {
+			continue
 		}
+		if match != nil {
+			return nil
+		}
+		match = a
 	}
+	return match
+}
 
-	// 反轉
-	for i, j := 0, len(running)-1; i < j; i, j = i+1, j-1 {
-		running[i], running[j] = running[j], running[i]
+// complete 標記代理完成並記錄結束時間；重複的完成事件（tool_result 與 SubagentStop）只記錄第一次
+func (t *tracker) complete(agent *AgentInfo, parsed map[string]interface{}, failed bool) {
+	if agent.Completed {
+		agent.Failed = agent.Failed || failed
+		return
 	}
+	agent.Completed = true
+	agent.Failed = failed
+	agent.EndTime = transcript.Timestamp(parsed)
+}
 
-	return running
+// start 記錄新啟動的代理
+func (t *tracker) start(toolID, parent string, block, parsed map[string]interface{}) {
+	input, _ := block["input"].(map[string]interface{})
+	desc, _ := input["description"].(string)
+	subType, _ := input["subagent_type"].(string)
+	if subType == "" {
+		subType = "Agent"
+	}
+
+	agent := &AgentInfo{
+		ID:          toolID,
+		ParentID:    parent,
+		Type:        subType,
+		Description: statusline.Truncate(desc, 40, "..."),
+		StartTime:   transcript.Timestamp(parsed),
+	}
+	if agent.StartTime.IsZero() {
+		agent.StartTime = t.now
+	}
+	if model, _ := input["model"].(string); model != "" {
+		agent.Model = shortModel(model)
+	}
+	if p, ok := t.agents[parent]; ok {
+		agent.Depth = p.Depth + 1
+	}
+	if _, exists := t.agents[toolID]; !exists {
+		t.order = append(t.order, toolID)
+	}
+	t.agents[toolID] = agent
 }
 
-// extractTimestamp 從 transcript 行提取時間戳
-func extractTimestamp(parsed map[string]interface{}, fallback time.Time) time.Time {
-	if ts, ok := parsed["timestamp"].(string); ok {
-		if t, err := time.Parse(time.RFC3339, ts); err == nil {
-			return t
+// ownerOf 找出此行所屬的代理（toolUseId）；主對話的行回傳空字串
+func (t *tracker) ownerOf(parsed map[string]interface{}) string {
+	agentID, _ := parsed["agentId"].(string)
+	if data, ok := parsed["data"].(map[string]interface{}); ok && agentID == "" {
+		agentID, _ = data["agentId"].(string)
+	}
+
+	// progress 行直接帶有啟動它的 tool_use ID
+	if parentTool, _ := parsed["parentToolUseID"].(string); parentTool != "" {
+		if _, ok := t.agents[parentTool]; ok {
+			t.bind(parentTool, agentID, parsed)
+			return parentTool
+		}
+	}
+
+	if isSide, _ := parsed["isSidechain"].(bool); !isSide {
+		return ""
+	}
+
+	if owner, ok := t.byAgentID[agentID]; ok && agentID != "" {
+		t.bind(owner, agentID, parsed)
+		return owner
+	}
+	if parentUUID, _ := parsed["parentUuid"].(string); parentUUID != "" {
+		if owner, ok := t.byUUID[parentUUID]; ok {
+			t.bind(owner, agentID, parsed)
+			return owner
+		}
+	}
+
+	// sidechain 的第一行沒有明確的對應：只有恰好一個執行中的代理尚未對應到 sidechain 時才歸屬給它。
+	// 並行啟動多個代理時無法判斷順序，寧可不歸屬也不要把 usage 與停止事件算到別的代理
+	candidate := ""
+	for _, id := range t.order {
+		if t.bound[id] || t.agents[id].Completed {
+			continue
+		}
+		if candidate != "" {
+			return ""
 		}
+		candidate = id
 	}
-	if ts, ok := parsed["timestamp"].(float64); ok {
-		return time.Unix(int64(ts/1000), 0)
+	if candidate != "" {
+		t.bind(candidate, agentID, parsed)
 	}
-	return fallback
+	return candidate
 }
 
-func truncateDesc(desc string, maxLen int) string {
-	if len(desc) <= maxLen {
-		return desc
+// bind 記錄 sidechain 行與代理的對應
+func (t *tracker) bind(owner, agentID string, parsed map[string]interface{}) {
+	t.bound[owner] = true
+	if agentID != "" {
+		t.byAgentID[agentID] = owner
+	}
+	if uuid, _ := parsed["uuid"].(string); uuid != "" {
+		t.byUUID[uuid] = owner
 	}
-	return desc[:maxLen-3] + "..."
 }
 
-// Format 格式化代理列表為顯示字串
-func Format(agents []AgentInfo) string {
-	if len(agents) == 0 {
-		return ""
+// attributeUsage 將 assistant 訊息的 usage 與模型累加到代理
+func (t *tracker) attributeUsage(owner string, msg map[string]interface{}) {
+	agent := t.agents[owner]
+	if agent == nil {
+		return
+	}
+	if model, _ := msg["model"].(string); model != "" && model != "<synthetic>" {
+		agent.Model = shortModel(model)
 	}
 
-	var parts []string
-	for _, a := range agents {
-		elapsed := formatElapsed(a.ElapsedSec)
-		icon := "◐"
+	usage, ok := msg["usage"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if id, _ := msg["id"].(string); id != "" {
+		key := owner + "/" + id
+		if t.seenMsg[key] {
+			return
+		}
+		t.seenMsg[key] = true
+	}
+	for _, key := range []string{"input_tokens", "cache_creation_input_tokens", "cache_read_input_tokens"} {
+		if v, ok := usage[key].(float64); ok {
+			agent.InputTokens += int(v)
+		}
+	}
+	if v, ok := usage["output_tokens"].(float64); ok {
+		agent.OutputTokens += int(v)
+	}
+}
+
+// running 收集未完成的代理，依代理樹深度優先排列並計算耗時。
+// 不在此限制數量，由 Format / FormatTree 顯示最近的 MaxAgents 個並提示其餘數量。
+func (t *tracker) running() []AgentInfo {
+	children := make(map[string][]string)
+	var roots []string
+	for _, id := range t.order {
+		a := t.agents[id]
 		if a.Completed {
-			icon = "✓"
+			continue
+		}
+		if p, ok := t.agents[a.ParentID]; ok && !p.Completed {
+			children[a.ParentID] = append(children[a.ParentID], id)
+		} else {
+			roots = append(roots, id)
+		}
+	}
+
+	var result []AgentInfo
+	var walk func(id string, depth int)
+	walk = func(id string, depth int) {
+		agent := *t.agents[id]
+		agent.Depth = depth
+		agent.ElapsedSec = int(t.now.Sub(agent.StartTime).Seconds())
+		if agent.ElapsedSec < 0 {
+			agent.ElapsedSec = 0
+		}
+		result = append(result, agent)
+		for _, child := range children[id] {
+			walk(child, depth+1)
+		}
+	}
+	for _, id := range roots {
+		walk(id, 0)
+	}
+	return result
+}
+
+// recent 收集在 linger 時間內完成的代理（最近完成的在後），ElapsedSec 為總耗時
+func (t *tracker) recent(linger time.Duration) []AgentInfo {
+	if linger <= 0 {
+		return nil
+	}
+	var result []AgentInfo
+	for _, id := range t.order {
+		a := t.agents[id]
+		if !a.Completed || a.EndTime.IsZero() || t.now.Sub(a.EndTime) > linger {
+			continue
 		}
+		agent := *a
+		agent.Depth = 0
+		agent.ElapsedSec = int(agent.EndTime.Sub(agent.StartTime).Seconds())
+		if agent.ElapsedSec < 0 {
+			agent.ElapsedSec = 0
+		}
+		result = append(result, agent)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].EndTime.Before(result[j].EndTime)
+	})
+	if len(result) > MaxCompletedAgents {
+		result = result[len(result)-MaxCompletedAgents:]
+	}
+	return result
+}
+
+// lineMessage 取得行內的訊息；progress 行的子代理訊息位於 data.message.message
+func lineMessage(parsed map[string]interface{}) map[string]interface{} {
+	if msg, ok := parsed["message"].(map[string]interface{}); ok {
+		return msg
+	}
+	data, ok := parsed["data"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	wrapper, ok := data["message"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	msg, _ := wrapper["message"].(map[string]interface{})
+	return msg
+}
+
+// shortModel 將模型 ID 轉為簡短名稱（claude-haiku-4-5 → Haiku）
+func shortModel(model string) string {
+	lower := strings.ToLower(model)
+	for _, family := range []string{"Opus", "Sonnet", "Haiku"} {
+		if strings.Contains(lower, strings.ToLower(family)) {
+			return family
+		}
+	}
+	return model
+}
 
-		model := ""
-		if a.Model != "" {
-			model = fmt.Sprintf(" [%s]", a.Model)
+// visible 依 MaxAgents 限制執行中代理的顯示數量，回傳顯示的代理與被省略的數量。
+// 以整棵子樹為單位保留最近啟動的根代理，不會只留下子代理而省略其上層；
+// 最近的子樹本身超過 MaxAgents 時保留其深度優先順序的前 MaxAgents 個（上層一定在子代理之前）。
+// 近期完成的代理不受此限制。
+func visible(agents []AgentInfo) ([]AgentInfo, int) {
+	var running, completed []AgentInfo
+	for _, a := range agents {
+		if a.Completed {
+			completed = append(completed, a)
+		} else {
+			running = append(running, a)
 		}
+	}
+	if len(running) <= MaxAgents {
+		return append(running, completed...), 0
+	}
 
-		desc := ""
-		if a.Description != "" {
-			desc = fmt.Sprintf(" \"%s\"", a.Description)
+	// 由後往前逐棵子樹（Depth 0 開頭）加入
+	start := len(running)
+	for end := len(running); end > 0; {
+		root := end - 1
+		for root > 0 && running[root].Depth > 0 {
+			root--
+		}
+		if end-root+(len(running)-end) > MaxAgents {
+			if end == len(running) {
+				// 最近的子樹就放不下：保留其前段
+				kept := running[root : root+MaxAgents]
+				return append(kept, completed...), len(running) - MaxAgents
+			}
+			break
 		}
+		start, end = root, root
+	}
+	kept := running[start:]
+	return append(kept, completed...), start
+}
+
+// formatHidden 被省略的執行中代理數量提示
+func formatHidden(hidden int) string {
+	return fmt.Sprintf("+%d more", hidden)
+}
+
+// Format 格式化代理列表為單行顯示字串（compact 模式）
+func Format(agents []AgentInfo) string {
+	if len(agents) == 0 {
+		return ""
+	}
 
-		parts = append(parts, fmt.Sprintf("%s %s%s%s %s", icon, a.Type, model, desc, elapsed))
+	shown, hidden := visible(agents)
+	var parts []string
+	for _, a := range shown {
+		parts = append(parts, formatAgent(a))
+	}
+	if hidden > 0 {
+		parts = append(parts, formatHidden(hidden))
 	}
 
 	return strings.Join(parts, "  ")
 }
 
+// FormatTree 格式化代理樹，每個代理一行，子代理以 └ 縮排（expanded 模式）
+func FormatTree(agents []AgentInfo) []string {
+	shown, hidden := visible(agents)
+	lines := make([]string, 0, len(shown)+1)
+	for _, a := range shown {
+		prefix := ""
+		if a.Depth > 0 {
+			prefix = strings.Repeat("  ", a.Depth-1) + "└ "
+		}
+		lines = append(lines, prefix+formatAgent(a))
+	}
+	if hidden > 0 {
+		lines = append(lines, formatHidden(hidden))
+	}
+	return lines
+}
+
+// formatAgent 格式化單一代理
+func formatAgent(a AgentInfo) string {
+	elapsed := formatElapsed(a.ElapsedSec)
+	icon := "◐"
+	switch {
+	case a.Failed:
+		icon = "✗"
+	case a.Completed:
+		icon = "✓"
+	}
+
+	model := ""
+	if a.Model != "" {
+		model = fmt.Sprintf(" [%s]", a.Model)
+	}
+
+	desc := ""
+	if a.Description != "" {
+		desc = fmt.Sprintf(" \"%s\"", a.Description)
+	}
+
+	tokens := ""
+	if total := a.TotalTokens(); total > 0 {
+		tokens = " " + formatTokens(total) + " tok"
+	}
+
+	return fmt.Sprintf("%s %s%s%s %s%s", icon, a.Type, model, desc, elapsed, tokens)
+}
+
+// formatTokens 將 token 數格式化為精簡字串（850 / 45.2k / 1.3M）
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
 func formatElapsed(seconds int) string {
 	if seconds < 1 {
 		return "<1s"
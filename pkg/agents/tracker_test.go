@@ -0,0 +1,352 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// agentCall 產生啟動代理的 assistant 行；extra 會合併到行的最上層（如 isSidechain、uuid）
+func agentCall(id, subType, desc string, extra map[string]interface{}) transcript.Line {
+	parsed := map[string]interface{}{
+		"timestamp": "2026-01-01T00:00:00Z",
+		"message": map[string]interface{}{
+			"role": "assistant",
+			"content": []interface{}{
+				map[string]interface{}{
+					"type":  "tool_use",
+					"id":    id,
+					"name":  "Agent",
+					"input": map[string]interface{}{"subagent_type": subType, "description": desc},
+				},
+			},
+		},
+	}
+	for k, v := range extra {
+		parsed[k] = v
+	}
+	return transcript.Line{Parsed: parsed}
+}
+
+// sidechainAssistant 產生子代理的 assistant 行（帶 usage 與模型）
+func sidechainAssistant(uuid, parentUUID, msgID, model string, input, output float64) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"isSidechain": true,
+		"uuid":        uuid,
+		"parentUuid":  parentUUID,
+		"message": map[string]interface{}{
+			"id":    msgID,
+			"role":  "assistant",
+			"model": model,
+			"usage": map[string]interface{}{"input_tokens": input, "output_tokens": output},
+		},
+	}}
+}
+
+// toolResult 產生完成指定 tool_use 的 user 行
+func toolResult(id string) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"message": map[string]interface{}{
+			"role":    "user",
+			"content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": id}},
+		},
+	}}
+}
+
+func TestAnalyzeSidechainUsageAndModel(t *testing.T) {
+	lines := []transcript.Line{
+		agentCall("toolu_1", "Explore", "find config", nil),
+		{Parsed: map[string]interface{}{"isSidechain": true, "uuid": "u1", "message": map[string]interface{}{"role": "user", "content": "find config"}}},
+		sidechainAssistant("u2", "u1", "msg_a", "claude-haiku-4-5-20251001", 1000, 200),
+		// 同一則訊息拆成多行時不重複計算
+		sidechainAssistant("u3", "u2", "msg_a", "claude-haiku-4-5-20251001", 1000, 200),
+		sidechainAssistant("u4", "u3", "msg_b", "claude-haiku-4-5-20251001", 3000, 500),
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 running agent, got %d", len(result))
+	}
+	a := result[0]
+	if a.Model != "Haiku" {
+		t.Errorf("expected model Haiku, got %q", a.Model)
+	}
+	if a.InputTokens != 4000 || a.OutputTokens != 700 {
+		t.Errorf("expected 4000/700 tokens, got %d/%d", a.InputTokens, a.OutputTokens)
+	}
+	if got := Format(result); !strings.Contains(got, "◐ Explore [Haiku] \"find config\"") || !strings.Contains(got, "4.7k tok") {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
+
+func TestAnalyzeNestedTree(t *testing.T) {
+	lines := []transcript.Line{
+		agentCall("toolu_parent", "general-purpose", "refactor", nil),
+		sidechainAssistant("p1", "", "msg_p", "claude-sonnet-4-5", 100, 10),
+		// 子代理在 sidechain 中呼叫 Agent
+		agentCall("toolu_child", "Explore", "scan", map[string]interface{}{"isSidechain": true, "uuid": "p2", "parentUuid": "p1"}),
+		// progress 行直接指出所屬的 tool_use
+		{Parsed: map[string]interface{}{
+			"type":            "progress",
+			"parentToolUseID": "toolu_child",
+			"data": map[string]interface{}{
+				"agentId": "agent-child",
+				"message": map[string]interface{}{
+					"message": map[string]interface{}{
+						"id":    "msg_c",
+						"role":  "assistant",
+						"model": "claude-haiku-4-5",
+						"usage": map[string]interface{}{"input_tokens": float64(50), "output_tokens": float64(5)},
+					},
+				},
+			},
+		}},
+		agentCall("toolu_other", "Plan", "plan", nil),
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 running agents, got %d", len(result))
+	}
+	wantIDs := []string{"toolu_parent", "toolu_child", "toolu_other"}
+	wantDepth := []int{0, 1, 0}
+	for i := range wantIDs {
+		if result[i].ID != wantIDs[i] || result[i].Depth != wantDepth[i] {
+			t.Errorf("result[%d] = %s depth %d, want %s depth %d", i, result[i].ID, result[i].Depth, wantIDs[i], wantDepth[i])
+		}
+	}
+	if result[1].ParentID != "toolu_parent" || result[1].Model != "Haiku" || result[1].TotalTokens() != 55 {
+		t.Errorf("unexpected child agent: %+v", result[1])
+	}
+	if result[0].TotalTokens() != 110 {
+		t.Errorf("expected parent tokens 110 (child usage not included), got %d", result[0].TotalTokens())
+	}
+
+	tree := FormatTree(result)
+	if len(tree) != 3 || !strings.HasPrefix(tree[1], "└ ◐ Explore") || strings.HasPrefix(tree[2], "└") {
+		t.Errorf("unexpected tree: %q", tree)
+	}
+}
+
+func TestAnalyzeCompletedAgentDropped(t *testing.T) {
+	lines := []transcript.Line{
+		agentCall("toolu_1", "Explore", "a", nil),
+		toolResult("toolu_1"),
+		agentCall("toolu_2", "Plan", "b", nil),
+	}
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 1 || result[0].ID != "toolu_2" {
+		t.Errorf("expected only toolu_2 running, got %+v", result)
+	}
+}
+
+func TestAnalyzeRecentCompletions(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+	at := func(l transcript.Line, ago time.Duration) transcript.Line {
+		l.Parsed["timestamp"] = ts(ago)
+		return l
+	}
+	failed := toolResult("toolu_fail")
+	failed.Parsed["message"].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})["is_error"] = true
+
+	lines := []transcript.Line{
+		at(agentCall("toolu_old", "Plan", "old", nil), 10*time.Minute),
+		at(toolResult("toolu_old"), 9*time.Minute),
+		at(agentCall("toolu_ok", "Explore", "scan", nil), 3*time.Minute),
+		at(toolResult("toolu_ok"), 46*time.Second),
+		at(agentCall("toolu_fail", "general-purpose", "fix", nil), 2*time.Minute),
+		at(failed, 10*time.Second),
+		at(agentCall("toolu_run", "Explore", "more", nil), 5*time.Second),
+	}
+
+	result := Analyze(lines, "s1", time.Minute)
+	if len(result) != 3 {
+		t.Fatalf("expected running + 2 recent agents, got %+v", result)
+	}
+	if result[0].ID != "toolu_run" || result[0].Completed {
+		t.Errorf("expected running agent first, got %+v", result[0])
+	}
+	ok, fail := result[1], result[2]
+	if ok.ID != "toolu_ok" || !ok.Completed || ok.Failed || ok.ElapsedSec != 134 {
+		t.Errorf("unexpected completed agent: %+v", ok)
+	}
+	if fail.ID != "toolu_fail" || !fail.Failed || fail.ElapsedSec != 110 {
+		t.Errorf("unexpected failed agent: %+v", fail)
+	}
+
+	got := Format(result)
+	if !strings.Contains(got, "✓ Explore \"scan\" 2m14s") || !strings.Contains(got, "✗ general-purpose \"fix\" 1m50s") {
+		t.Errorf("unexpected format: %q", got)
+	}
+
+	// linger 為 0 時完成的代理立即隱藏
+	if result := Analyze(lines, "s1", 0); len(result) != 1 {
+		t.Errorf("expected only running agent without linger, got %d", len(result))
+	}
+}
+
+// sidechainWithAgentID 產生帶 agentId 的 sidechain 行（子代理的第一則訊息）
+func sidechainWithAgentID(uuid, agentID string) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"isSidechain": true,
+		"uuid":        uuid,
+		"agentId":     agentID,
+		"message":     map[string]interface{}{"role": "user", "content": "task"},
+	}}
+}
+
+// subagentStop 產生 SubagentStop hook 事件行
+func subagentStop(agentID, agentType string) transcript.Line {
+	parsed := map[string]interface{}{"hook_event_name": "SubagentStop", "agent_type": agentType}
+	if agentID != "" {
+		parsed["agentId"] = agentID
+	}
+	return transcript.Line{Parsed: parsed}
+}
+
+func TestSubagentStopMatchesByAgentID(t *testing.T) {
+	lines := []transcript.Line{
+		agentCall("toolu_a", "Explore", "first", nil),
+		sidechainWithAgentID("a1", "agent-a"),
+		agentCall("toolu_b", "Explore", "second", nil),
+		sidechainWithAgentID("b1", "agent-b"),
+		// 第二個 Explore 先結束：以類型比對會誤標第一個
+		subagentStop("agent-b", "Explore"),
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 1 || result[0].ID != "toolu_a" {
+		t.Fatalf("expected only toolu_a running, got %+v", result)
+	}
+}
+
+func TestParallelLaunchesNotGuessed(t *testing.T) {
+	withUsage := func(l transcript.Line, msgID string) transcript.Line {
+		l.Parsed["message"] = map[string]interface{}{
+			"id":    msgID,
+			"role":  "assistant",
+			"model": "claude-haiku-4-5",
+			"usage": map[string]interface{}{"input_tokens": float64(100), "output_tokens": float64(10)},
+		}
+		return l
+	}
+	lines := []transcript.Line{
+		// 兩個代理都在任何 sidechain 行之前啟動
+		agentCall("toolu_a", "Explore", "first", nil),
+		agentCall("toolu_b", "Explore", "second", nil),
+		withUsage(sidechainWithAgentID("a1", "agent-a"), "msg_a"),
+		withUsage(sidechainWithAgentID("b1", "agent-b"), "msg_b"),
+		subagentStop("agent-a", "Explore"),
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 2 {
+		t.Fatalf("expected both agents still running, got %+v", result)
+	}
+	for _, a := range result {
+		if a.TotalTokens() != 0 || a.Model != "" {
+			t.Errorf("ambiguous sidechain usage should not be attributed: %+v", a)
+		}
+	}
+
+	// 帶 parentToolUseID 的 progress 行可明確對應，之後的停止事件完成正確的代理
+	progress := func(toolID, agentID string) transcript.Line {
+		return transcript.Line{Parsed: map[string]interface{}{
+			"type":            "progress",
+			"parentToolUseID": toolID,
+			"data":            map[string]interface{}{"agentId": agentID},
+		}}
+	}
+	lines = []transcript.Line{
+		agentCall("toolu_a", "Explore", "first", nil),
+		agentCall("toolu_b", "Explore", "second", nil),
+		progress("toolu_a", "agent-a"),
+		progress("toolu_b", "agent-b"),
+		subagentStop("agent-a", "Explore"),
+	}
+	if result := Analyze(lines, "s1", 0); len(result) != 1 || result[0].ID != "toolu_b" {
+		t.Errorf("expected only toolu_b running, got %+v", result)
+	}
+}
+
+func TestSubagentStopAgentIDFromToolUseResult(t *testing.T) {
+	done := toolResult("toolu_a")
+	done.Parsed["toolUseResult"] = map[string]interface{}{"agentId": "agent-a", "status": "completed"}
+	lines := []transcript.Line{
+		agentCall("toolu_a", "Explore", "first", nil),
+		done,
+		agentCall("toolu_b", "Explore", "second", nil),
+		// 已完成代理的重複停止事件不應波及仍在執行的同類型代理
+		subagentStop("agent-a", "Explore"),
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 1 || result[0].ID != "toolu_b" {
+		t.Fatalf("expected toolu_b still running, got %+v", result)
+	}
+}
+
+func TestSubagentStopByTypeOnlyWhenUnambiguous(t *testing.T) {
+	parallel := []transcript.Line{
+		agentCall("toolu_a", "Explore", "first", nil),
+		agentCall("toolu_b", "Explore", "second", nil),
+		subagentStop("", "Explore"),
+	}
+	if result := Analyze(parallel, "s1", 0); len(result) != 2 {
+		t.Errorf("expected ambiguous type-only stop to be ignored, got %d running", len(result))
+	}
+
+	single := []transcript.Line{
+		agentCall("toolu_a", "Explore", "first", nil),
+		agentCall("toolu_b", "Plan", "second", nil),
+		subagentStop("", "Explore"),
+	}
+	if result := Analyze(single, "s1", 0); len(result) != 1 || result[0].ID != "toolu_b" {
+		t.Errorf("expected unambiguous type-only stop to complete toolu_a, got %+v", result)
+	}
+}
+
+func TestFormatMoreAgents(t *testing.T) {
+	var lines []transcript.Line
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		lines = append(lines, agentCall("toolu_"+id, "Explore", id, nil))
+	}
+
+	result := Analyze(lines, "s1", 0)
+	if len(result) != 7 {
+		t.Fatalf("expected all 7 running agents, got %d", len(result))
+	}
+
+	got := Format(result)
+	if !strings.HasSuffix(got, "+4 more") || strings.Contains(got, "\"a\"") || !strings.Contains(got, "\"g\"") {
+		t.Errorf("expected latest agents with +4 more, got %q", got)
+	}
+	tree := FormatTree(result)
+	if len(tree) != MaxAgents+1 || tree[len(tree)-1] != "+4 more" {
+		t.Errorf("unexpected tree: %q", tree)
+	}
+}
+
+func TestFormatTreeKeepsParentsWhenTrimming(t *testing.T) {
+	agent := func(id string, depth int) AgentInfo {
+		return AgentInfo{ID: id, Type: "Explore", Description: id, Depth: depth}
+	}
+
+	// 最近的子樹本身超過 MaxAgents：保留上層與前面的子代理
+	nested := []AgentInfo{agent("p", 0), agent("c1", 1), agent("c2", 1), agent("c3", 1)}
+	tree := FormatTree(nested)
+	want := []string{`◐ Explore "p" <1s`, `└ ◐ Explore "c1" <1s`, `└ ◐ Explore "c2" <1s`, "+1 more"}
+	if strings.Join(tree, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected tree:\n%s", strings.Join(tree, "\n"))
+	}
+
+	// 以整棵子樹為單位省略較早的根代理
+	forest := []AgentInfo{agent("a", 0), agent("a1", 1), agent("b", 0), agent("b1", 1), agent("b2", 2)}
+	shown, hidden := visible(forest)
+	if hidden != 2 || len(shown) != 3 || shown[0].ID != "b" || shown[2].ID != "b2" {
+		t.Errorf("expected subtree b with 2 hidden, got %+v (+%d)", shown, hidden)
+	}
+}
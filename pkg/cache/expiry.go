@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// Prompt cache 的存活時間：預設 5 分鐘，extended caching 為 1 小時
+const (
+	DefaultTTL  = 5 * time.Minute
+	ExtendedTTL = time.Hour
+)
+
+// ExpiryWarning 剩餘時間低於此值時以紅色顯示
+const ExpiryWarning = time.Minute
+
+// ExpiryInfo 快取過期倒數資訊
+type ExpiryInfo struct {
+	TTL       time.Duration
+	LastTurn  time.Time     // 最後一個主對話 assistant 回應的時間（快取在每次使用時重新計時）
+	Remaining time.Duration // 距離過期的時間；<= 0 代表已過期
+}
+
+// Expired 快取是否已過期
+func (e *ExpiryInfo) Expired() bool {
+	return e.Remaining <= 0
+}
+
+// CalculateExpiry 以最後一個主對話 assistant 回應的時間與 usage.cache_creation 的 TTL 分類，
+// 推算 prompt cache 過期的時間；沒有使用快取時回傳 nil
+func CalculateExpiry(lines []transcript.Line, now time.Time) *ExpiryInfo {
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		if l.Parsed == nil {
+			continue
+		}
+		if isSide, ok := l.Parsed["isSidechain"].(bool); ok && isSide {
+			continue
+		}
+		msg, ok := l.Parsed["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if role, _ := msg["role"].(string); role != "assistant" {
+			continue
+		}
+		usage, ok := msg["usage"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		counts := transcript.ParseUsage(usage)
+		if counts.CacheRead+counts.CacheCreation <= 0 {
+			// 最新的回應沒有使用快取（例如太短無法快取），無從倒數
+			return nil
+		}
+		last := l.Time()
+		if last.IsZero() {
+			return nil
+		}
+
+		ttl := cacheTTL(lines[:i+1])
+		return &ExpiryInfo{TTL: ttl, LastTurn: last, Remaining: last.Add(ttl).Sub(now)}
+	}
+	return nil
+}
+
+// cacheTTL 由最近一則帶 cache_creation 分類的 usage 判斷快取 TTL：有寫入 1 小時快取即視為 extended
+func cacheTTL(lines []transcript.Line) time.Duration {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].Parsed == nil {
+			continue
+		}
+		msg, _ := lines[i].Parsed["message"].(map[string]interface{})
+		usage, _ := msg["usage"].(map[string]interface{})
+		creation, ok := usage["cache_creation"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		oneHour, _ := creation["ephemeral_1h_input_tokens"].(float64)
+		fiveMin, _ := creation["ephemeral_5m_input_tokens"].(float64)
+		if oneHour > 0 {
+			return ExtendedTTL
+		}
+		if fiveMin > 0 {
+			return DefaultTTL
+		}
+	}
+	return DefaultTTL
+}
+
+// FormatExpiry 格式化過期倒數（如 "cache ❄ in 2m"、"cache ❄ in 40s"、"cache ❄ cold"）
+func FormatExpiry(info *ExpiryInfo) string {
+	if info == nil {
+		return ""
+	}
+	if info.Expired() {
+		return "cache ❄ cold"
+	}
+	remaining := info.Remaining
+	if remaining < time.Minute {
+		return fmt.Sprintf("cache ❄ in %ds", int(remaining.Seconds()))
+	}
+	return fmt.Sprintf("cache ❄ in %dm", int(remaining.Minutes()))
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+func assistantUsage(ts string, side bool, read float64, creation map[string]interface{}) transcript.Line {
+	usage := map[string]interface{}{"input_tokens": float64(5), "cache_read_input_tokens": read}
+	if creation != nil {
+		usage["cache_creation"] = creation
+		usage["cache_creation_input_tokens"] = creation["ephemeral_5m_input_tokens"].(float64) + creation["ephemeral_1h_input_tokens"].(float64)
+	}
+	return makeLine("", map[string]interface{}{
+		"timestamp":   ts,
+		"isSidechain": side,
+		"message":     map[string]interface{}{"role": "assistant", "usage": usage},
+	})
+}
+
+func TestCalculateExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fiveMin := map[string]interface{}{"ephemeral_5m_input_tokens": float64(3000), "ephemeral_1h_input_tokens": float64(0)}
+	oneHour := map[string]interface{}{"ephemeral_5m_input_tokens": float64(0), "ephemeral_1h_input_tokens": float64(3000)}
+
+	lines := []transcript.Line{
+		assistantUsage("2026-01-01T11:57:00Z", false, 0, fiveMin),
+		assistantUsage("2026-01-01T11:57:50Z", false, 9000, nil),
+		// 子代理的回應不影響主對話的快取
+		assistantUsage("2026-01-01T11:59:30Z", true, 100, nil),
+	}
+	info := CalculateExpiry(lines, now)
+	if info == nil || info.TTL != DefaultTTL || info.Remaining != 2*time.Minute+50*time.Second {
+		t.Fatalf("unexpected expiry: %+v", info)
+	}
+	if got := FormatExpiry(info); got != "cache ❄ in 2m" {
+		t.Errorf("unexpected format: %q", got)
+	}
+
+	// 近過期：秒數顯示
+	if got := FormatExpiry(CalculateExpiry(lines, now.Add(2*time.Minute+10*time.Second))); got != "cache ❄ in 40s" {
+		t.Errorf("unexpected near-expiry format: %q", got)
+	}
+	// 已過期
+	expired := CalculateExpiry(lines, now.Add(10*time.Minute))
+	if !expired.Expired() || FormatExpiry(expired) != "cache ❄ cold" {
+		t.Errorf("expected cold cache, got %+v", expired)
+	}
+
+	// 1 小時快取
+	extended := []transcript.Line{assistantUsage("2026-01-01T11:50:00Z", false, 0, oneHour)}
+	if info := CalculateExpiry(extended, now); info.TTL != ExtendedTTL || FormatExpiry(info) != "cache ❄ in 50m" {
+		t.Errorf("unexpected extended expiry: %+v", info)
+	}
+}
+
+func TestCalculateExpiryNoCache(t *testing.T) {
+	lines := []transcript.Line{assistantUsage("2026-01-01T11:57:00Z", false, 0, nil)}
+	if info := CalculateExpiry(lines, time.Now()); info != nil {
+		t.Errorf("expected nil without cache usage, got %+v", info)
+	}
+	if FormatExpiry(nil) != "" {
+		t.Error("expected empty string for nil")
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// 快取 token 相對於一般輸入 token 的計價倍率；快取寫入依 TTL 計價
+const (
+	CacheWrite5mMultiplier = 1.25
+	CacheWrite1hMultiplier = 2
+	CacheReadMultiplier    = 0.1
+)
+
+// modelPrice 模型 ID 片段對應的輸入 token 價格（USD / 百萬 tokens）
+type modelPrice struct {
+	match string
+	input float64
+}
+
+// defaultPrices 內建的輸入價格表（只列出已公布價格的模型，其他模型以 model_prices 設定），
+// 依序以 strings.Contains 比對，較具體的片段放前面
+var defaultPrices = []modelPrice{
+	{"opus-4-5", 5},
+	{"opus", 15},
+	{"sonnet", 3},
+	{"haiku-4", 1},
+	{"3-5-haiku", 0.8},
+	{"haiku", 0.25},
+}
+
+// customPrices 使用者設定的價格（優先於內建表），只在啟動時寫入
+var customPrices []modelPrice
+
+// SetPrices 設定自訂輸入價格（key 為模型 ID 片段，值為 USD / 百萬 tokens），較長的片段優先比對
+func SetPrices(prices map[string]float64) {
+	customPrices = customPrices[:0]
+	for match, input := range prices {
+		customPrices = append(customPrices, modelPrice{match: match, input: input})
+	}
+	sort.Slice(customPrices, func(i, j int) bool {
+		if len(customPrices[i].match) != len(customPrices[j].match) {
+			return len(customPrices[i].match) > len(customPrices[j].match)
+		}
+		return customPrices[i].match < customPrices[j].match
+	})
+}
+
+// InputPrice 取得模型的輸入 token 價格（USD / 百萬 tokens），未知模型回傳 0
+func InputPrice(model string) float64 {
+	for _, table := range [][]modelPrice{customPrices, defaultPrices} {
+		for _, p := range table {
+			if strings.Contains(model, p.match) {
+				return p.input
+			}
+		}
+	}
+	return 0
+}
+
+// Savings 計算相對於不使用快取（所有輸入都以一般價格計費）節省的金額（USD），
+// 快取寫入依 5 分鐘／1 小時 TTL 分別計價；寫入的額外成本大於讀取省下的金額時為負值
+func Savings(model string, u transcript.Usage) float64 {
+	price := InputPrice(model) / 1e6
+	write5m := u.CacheCreation - u.CacheCreation1h
+	return price * (float64(u.CacheRead)*(1-CacheReadMultiplier) -
+		float64(write5m)*(CacheWrite5mMultiplier-1) -
+		float64(u.CacheCreation1h)*(CacheWrite1hMultiplier-1))
+}
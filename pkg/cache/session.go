@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"sort"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// bustingWindow 判斷快取失效時檢視的最近主對話回應數
+const bustingWindow = transcript.RecentUsageWindow
+
+// bustingMinCreation 最近回應的快取寫入量低於此值時不警告（小幅新增內容的正常寫入）
+const bustingMinCreation = 20000
+
+// sessionInfo 將 session 的 usage 統計轉為顯示用的 CacheInfo；沒有任何 usage 時回傳 nil
+func sessionInfo(s *transcript.SessionScan) *CacheInfo {
+	var total transcript.Usage
+	var savings float64
+	models := make([]string, 0, len(s.Models))
+	for model := range s.Models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		c := s.Models[model]
+		total.Uncached += c.Uncached
+		total.CacheRead += c.CacheRead
+		total.CacheCreation += c.CacheCreation
+		savings += Savings(model, *c)
+	}
+	if total.Total() <= 0 {
+		return nil
+	}
+
+	return &CacheInfo{
+		HitRate:       int(total.CacheRead * 100 / total.Total()),
+		CacheRead:     int(total.CacheRead),
+		CacheCreation: int(total.CacheCreation),
+		Uncached:      int(total.Uncached),
+		TotalInput:    int(total.Total()),
+		SavingsUSD:    savings,
+		Busting:       busting(s.Recent),
+	}
+}
+
+// busting 最近的主對話回應持續以快取寫入為主（例如 session 中途修改 CLAUDE.md 讓前綴失效）。
+// 需要滿 bustingWindow 個回應，session 開頭的正常暖機寫入不會觸發。
+func busting(recent []transcript.Usage) bool {
+	if len(recent) < bustingWindow {
+		return false
+	}
+	var read, creation int64
+	for _, c := range recent {
+		read += c.CacheRead
+		creation += c.CacheCreation
+	}
+	return creation >= bustingMinCreation && creation > read
+}
+
+// Session 增量掃描整份 transcript（與 latency 共用 transcript.ScanSession），回傳 session 累計的快取效率
+func Session(transcriptPath, sessionID string) *CacheInfo {
+	scan := transcript.ScanSession(transcriptPath, sessionID)
+	if scan == nil {
+		return nil
+	}
+	return sessionInfo(scan)
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+func usageLine(id, model string, side bool, uncached, read, creation float64) transcript.Line {
+	return makeLine("", map[string]interface{}{
+		"isSidechain": side,
+		"message": map[string]interface{}{
+			"id":    id,
+			"role":  "assistant",
+			"model": model,
+			"usage": map[string]interface{}{
+				"input_tokens":                uncached,
+				"cache_read_input_tokens":     read,
+				"cache_creation_input_tokens": creation,
+			},
+		},
+	})
+}
+
+func TestSessionInfoCumulative(t *testing.T) {
+	stats := transcript.NewSessionScan("")
+	stats.Add([]transcript.Line{
+		usageLine("m1", "claude-sonnet-4-5", false, 100, 0, 20000),
+		// 同一則訊息拆成多行時只計算一次
+		usageLine("m1", "claude-sonnet-4-5", false, 100, 0, 20000),
+		usageLine("m2", "claude-sonnet-4-5", false, 100, 1000000, 1000),
+		usageLine("m3", "claude-haiku-4-5", true, 0, 200000, 0),
+	})
+
+	info := sessionInfo(stats)
+	if info == nil {
+		t.Fatal("expected CacheInfo")
+	}
+	if info.CacheRead != 1200000 || info.CacheCreation != 21000 || info.Uncached != 200 {
+		t.Errorf("unexpected counts: %+v", info)
+	}
+	if info.HitRate != 98 {
+		t.Errorf("expected hit rate 98, got %d", info.HitRate)
+	}
+	// sonnet: 3/M × (1,000,000×0.9 − 21,000×0.25) = 2.68425；haiku 4.5: 1/M × 200,000×0.9 = 0.18
+	if math.Abs(info.SavingsUSD-2.86425) > 1e-9 {
+		t.Errorf("expected savings 2.86425, got %f", info.SavingsUSD)
+	}
+	if info.Busting {
+		t.Error("did not expect busting warning")
+	}
+	if got := Format(info); got != "Cache 98% saved $2.86" {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
+
+func TestSessionInfoBusting(t *testing.T) {
+	stats := transcript.NewSessionScan("")
+	stats.Add([]transcript.Line{usageLine("warm", "claude-sonnet-4-5", false, 10, 0, 50000)})
+	for _, id := range []string{"a", "b", "c", "d"} {
+		stats.Add([]transcript.Line{usageLine(id, "claude-sonnet-4-5", false, 10, 50000, 0)})
+	}
+	if sessionInfo(stats).Busting {
+		t.Fatal("warm-up cache writes should not trigger busting")
+	}
+
+	// 每一輪都重新寫入整個前綴
+	for _, id := range []string{"e", "f", "g", "h"} {
+		stats.Add([]transcript.Line{usageLine(id, "claude-sonnet-4-5", false, 10, 2000, 52000)})
+	}
+	info := sessionInfo(stats)
+	if !info.Busting {
+		t.Fatalf("expected busting warning, got %+v", info)
+	}
+	if got := Format(info); !strings.HasSuffix(got, "⚠ busting") {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
+
+func TestSavingsByCacheTTL(t *testing.T) {
+	// 1,000,000 tokens 寫入，其中 400,000 寫入 1 小時快取
+	line := makeLine("", map[string]interface{}{
+		"message": map[string]interface{}{
+			"id":    "m1",
+			"role":  "assistant",
+			"model": "claude-sonnet-4-5",
+			"usage": map[string]interface{}{
+				"cache_creation_input_tokens": float64(1000000),
+				"cache_creation": map[string]interface{}{
+					"ephemeral_5m_input_tokens": float64(600000),
+					"ephemeral_1h_input_tokens": float64(400000),
+				},
+			},
+		},
+	})
+	stats := transcript.NewSessionScan("")
+	stats.Add([]transcript.Line{line})
+
+	// 3/M × −(600,000×0.25 + 400,000×1.0) = −1.65
+	if got := sessionInfo(stats).SavingsUSD; math.Abs(got+1.65) > 1e-9 {
+		t.Errorf("expected savings -1.65, got %f", got)
+	}
+}
+
+func TestSessionIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	line := `{"message":{"id":"%s","role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"cache_read_input_tokens":90,"cache_creation_input_tokens":0}}}` + "\n"
+
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(line, "%s", "m1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info := Session(path, "s1"); info == nil || info.TotalInput != 100 {
+		t.Fatalf("unexpected first scan: %+v", info)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(strings.ReplaceAll(line, "%s", "m2"))
+	f.Close()
+
+	if info := Session(path, "s1"); info == nil || info.TotalInput != 200 || info.HitRate != 90 {
+		t.Errorf("unexpected incremental scan: %+v", info)
+	}
+}
+
+func TestInputPrice(t *testing.T) {
+	cases := map[string]float64{
+		"claude-opus-4-1-20250805":   15,
+		"claude-opus-4-5-20251101":   5,
+		"claude-sonnet-4-5-20250929": 3,
+		"claude-haiku-4-5-20251001":  1,
+		"claude-3-5-haiku-20241022":  0.8,
+		"unknown-model":              0,
+	}
+	for model, want := range cases {
+		if got := InputPrice(model); got != want {
+			t.Errorf("InputPrice(%q) = %v, want %v", model, got, want)
+		}
+	}
+
+	SetPrices(map[string]float64{"sonnet": 2, "my-proxy": 4})
+	defer SetPrices(nil)
+	if InputPrice("claude-sonnet-4-5") != 2 || InputPrice("my-proxy-model") != 4 {
+		t.Error("expected custom prices to take precedence")
+	}
+}
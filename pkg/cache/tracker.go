@@ -8,9 +8,13 @@ import (
 
 // CacheInfo 代表快取命中率資訊
 type CacheInfo struct {
-	HitRate    int // 0-100 百分比
-	CacheRead  int // cache_read_input_tokens
-	TotalInput int // 三種 token 的總和
+	HitRate       int     // 0-100 百分比
+	CacheRead     int     // cache_read_input_tokens
+	CacheCreation int     // cache_creation_input_tokens
+	Uncached      int     // input_tokens
+	TotalInput    int     // 三種 token 的總和
+	SavingsUSD    float64 // 相對於不使用快取節省的金額；快取寫入成本較高時為負值
+	Busting       bool    // 最近的回應以快取寫入為主，快取前綴可能一再失效
 }
 
 // Calculate 從 transcript 行計算快取命中率
@@ -35,38 +39,39 @@ func Calculate(lines []transcript.Line) *CacheInfo {
 			continue
 		}
 
-		var inputTokens, cacheRead, cacheCreation float64
-
-		if v, ok := usage["input_tokens"].(float64); ok {
-			inputTokens = v
-		}
-		if v, ok := usage["cache_read_input_tokens"].(float64); ok {
-			cacheRead = v
-		}
-		if v, ok := usage["cache_creation_input_tokens"].(float64); ok {
-			cacheCreation = v
-		}
-
-		total := inputTokens + cacheRead + cacheCreation
+		counts := transcript.ParseUsage(usage)
+		total := counts.Total()
 		if total <= 0 {
 			continue
 		}
 
-		hitRate := int(cacheRead * 100.0 / total)
 		return &CacheInfo{
-			HitRate:    hitRate,
-			CacheRead:  int(cacheRead),
-			TotalInput: int(total),
+			HitRate:       int(counts.CacheRead * 100 / total),
+			CacheRead:     int(counts.CacheRead),
+			CacheCreation: int(counts.CacheCreation),
+			Uncached:      int(counts.Uncached),
+			TotalInput:    int(total),
 		}
 	}
 
 	return nil
 }
 
-// Format 格式化快取命中率顯示
+// Format 格式化快取命中率顯示；有節省金額時附上，快取疑似一再失效時加上警告
+// （如 "Cache 92% saved $1.23"、"Cache 31% +$0.40 ⚠ busting"）
 func Format(info *CacheInfo) string {
 	if info == nil {
 		return ""
 	}
-	return fmt.Sprintf("Cache %d%%", info.HitRate)
+	s := fmt.Sprintf("Cache %d%%", info.HitRate)
+	switch {
+	case info.SavingsUSD >= 0.01:
+		s += fmt.Sprintf(" saved $%.2f", info.SavingsUSD)
+	case info.SavingsUSD <= -0.01:
+		s += fmt.Sprintf(" +$%.2f", -info.SavingsUSD)
+	}
+	if info.Busting {
+		s += " ⚠ busting"
+	}
+	return s
 }
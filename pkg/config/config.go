@@ -28,6 +28,41 @@ type Config struct {
 	SeparatorStyle string            `json:"separator_style"` // "pipe", "powerline", "nerdfont"
 	OverflowMode   string            `json:"overflow_mode"`   // "wrap" or "truncate" (default: "wrap"); unknown values fall back to "wrap" with a stderr warning
 	Sections       SectionVisibility `json:"sections"`
+	// TerminalWidth 固定的終端寬度；0 代表自動偵測（CLAUDE_STATUSLINE_WIDTH、COLUMNS、ioctl、tmux…）
+	TerminalWidth int `json:"terminal_width"`
+	// ToolWarnSeconds 工具執行超過指定秒數時醒目標示，key 為工具名稱，"*" 為其他工具的預設值；0 代表不警告
+	ToolWarnSeconds map[string]int `json:"tool_warn_seconds"`
+	// ToolTargets 自訂工具的目標顯示模板，key 為工具名稱，值以 {欄位} 引用工具輸入，如 "{database}: {sql}"
+	ToolTargets map[string]string `json:"tool_targets"`
+	// AgentLingerSeconds 子代理完成後仍以 ✓/✗ 顯示的秒數；0 代表完成即隱藏
+	AgentLingerSeconds int `json:"agent_linger_seconds"`
+	// TodoMode "summary"（預設，只顯示進行中項目與完成數）或 "full"（expanded 模式下列出完整清單）
+	TodoMode string `json:"todo_mode"`
+	// TodoMaxRows 完整清單模式最多顯示的項目數
+	TodoMaxRows int `json:"todo_max_rows"`
+	// SpeedWindowTurns 輸出速度滾動平均採用的最近回合數
+	SpeedWindowTurns int `json:"speed_window_turns"`
+	// ModelPrices 覆寫模型輸入 token 價格（USD / 百萬 tokens），key 為模型 ID 片段，用於計算快取節省金額
+	ModelPrices map[string]float64 `json:"model_prices"`
+	// TodoTools 覆寫任務工具名稱；未設定的類別沿用內建名稱
+	TodoTools TodoTools `json:"todo_tools"`
+	// Hyperlinks OSC 8 超連結："auto"（預設，依終端偵測）、"on" 或 "off"
+	Hyperlinks string `json:"hyperlinks"`
+	// BranchURL 分支連結樣板，可用 {repo_url}、{branch}；空字串代表 <倉庫網頁>/tree/<branch>
+	BranchURL string `json:"branch_url"`
+	// PRURL PR 連結樣板，可用 {repo_url}、{number}、{branch}；空字串代表使用偵測到的 PR 網址
+	PRURL string `json:"pr_url"`
+	// Background 終端背景："auto"（預設，以 COLORFGBG 偵測）、"query"（另以 OSC 11 詢問終端）、"light" 或 "dark"，決定使用的配色
+	Background string `json:"background"`
+	// GitCounts git 狀態的檔案統計："kind"（預設，!modified +added ✘deleted）或 "staged"（●staged !unstaged）
+	GitCounts string `json:"git_counts"`
+}
+
+// TodoTools 任務追蹤工具的名稱設定
+type TodoTools struct {
+	Snapshot []string `json:"snapshot"` // 每次帶完整清單的工具（如 TodoWrite）
+	Create   []string `json:"create"`   // 新增單一任務的工具（如 TaskCreate）
+	Update   []string `json:"update"`   // 依任務 ID 更新狀態的工具（如 TaskUpdate）
 }
 
 // GetSeparator 取得目前的分隔符設定
@@ -53,33 +88,54 @@ func (c *Config) GetSeparator() SeparatorStyle {
 
 // SectionVisibility 各區段的可見性設定
 type SectionVisibility struct {
-	Model        bool `json:"model"`
-	Git          bool `json:"git"`
-	GitStatus    bool `json:"git_status"`
-	Context      bool `json:"context"`
-	Session      bool `json:"session"`
-	Cost         bool `json:"cost"`
-	Tools        bool `json:"tools"`
-	Agents       bool `json:"agents"`
-	Todo         bool `json:"todo"`
-	APILimits    bool `json:"api_limits"`
-	Speed        bool `json:"speed"`
-	SessionName  bool `json:"session_name"`
-	ConfigInfo   bool `json:"config_info"`
-	Autocompact  bool `json:"autocompact"`
-	CacheHitRate bool `json:"cache_hit_rate"`
-	UserMessage  bool `json:"user_message"`
+	Model         bool `json:"model"`
+	Git           bool `json:"git"`
+	GitStatus     bool `json:"git_status"`
+	GitStash      bool `json:"git_stash"`       // git_status 子欄位：stash 數量
+	GitDiffStat   bool `json:"git_diff_stat"`   // git_status 子欄位：working tree 行數變化
+	GitLastCommit bool `json:"git_last_commit"` // git_status 子欄位：最後 commit 時間與標題
+	PR            bool `json:"pr"`              // 目前關聯的 pull request 編號
+	Context       bool `json:"context"`
+	Session       bool `json:"session"`
+	Cost          bool `json:"cost"`
+	Tools         bool `json:"tools"`
+	Agents        bool `json:"agents"`
+	Todo          bool `json:"todo"`
+	APILimits     bool `json:"api_limits"`
+	Speed         bool `json:"speed"`
+	SessionName   bool `json:"session_name"`
+	ConfigInfo    bool `json:"config_info"`
+	Autocompact   bool `json:"autocompact"`
+	CacheHitRate  bool `json:"cache_hit_rate"`
+	CacheExpiry   bool `json:"cache_expiry"` // prompt cache 過期倒數
+	UserMessage   bool `json:"user_message"`
+	ToolUsage     bool `json:"tool_usage"` // 整個 session 的工具呼叫次數與失敗數
+	Latency       bool `json:"latency"`    // 平均 API 時間、API 時間佔比與 TTFT 估計
 }
 
-// DefaultConfig 返回預設配置（所有區段可見）
+// DefaultConfig 返回預設配置（除較佔寬度的 git_last_commit、tool_usage、latency 外，所有區段可見）
 func DefaultConfig() *Config {
 	return &Config{
-		DisplayMode:  "expanded",
-		OverflowMode: "wrap",
+		DisplayMode:        "expanded",
+		OverflowMode:       "wrap",
+		AgentLingerSeconds: 30,
+		TodoMode:           "summary",
+		TodoMaxRows:        5,
+		Hyperlinks:         "auto",
+		Background:         "auto",
+		GitCounts:          "kind",
+		SpeedWindowTurns:   5,
+		ToolWarnSeconds: map[string]int{
+			"Bash": 120,
+			"*":    300,
+		},
 		Sections: SectionVisibility{
 			Model:        true,
 			Git:          true,
 			GitStatus:    true,
+			GitStash:     true,
+			GitDiffStat:  true,
+			PR:           true,
 			Context:      true,
 			Session:      true,
 			Cost:         true,
@@ -92,6 +148,7 @@ func DefaultConfig() *Config {
 			ConfigInfo:   true,
 			Autocompact:  true,
 			CacheHitRate: true,
+			CacheExpiry:  true,
 			UserMessage:  true,
 		},
 	}
@@ -103,3 +103,27 @@ func TestLoadOverflowModeTruncate(t *testing.T) {
 		return
 	}
 }
+
+func TestLoadToolWarnSecondsMerge(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	configDir := filepath.Join(dir, ".claude", "omystatusline")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	configJSON := `{"tool_warn_seconds":{"Bash":60,"WebFetch":30}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(configJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Load()
+	if cfg.ToolWarnSeconds["Bash"] != 60 || cfg.ToolWarnSeconds["WebFetch"] != 30 {
+		t.Fatalf("expected overridden thresholds, got %v", cfg.ToolWarnSeconds)
+	}
+	// 未覆蓋的預設門檻應保留
+	if cfg.ToolWarnSeconds["*"] != 300 {
+		t.Fatalf("expected default '*' threshold 300 to be kept, got %v", cfg.ToolWarnSeconds)
+	}
+}
@@ -250,20 +250,6 @@ func calculateUsageFromLines(lines []transcript.Line) int {
 	return tokens
 }
 
-// gradientColors 定義 10 格漸層色（綠→黃→橙→紅）
-var gradientColors = [10]string{
-	"\033[38;2;76;175;80m",  // green
-	"\033[38;2;108;175;72m", // green-yellow
-	"\033[38;2;139;175;64m", // yellow-green
-	"\033[38;2;171;175;56m", // yellow
-	"\033[38;2;202;165;48m", // gold
-	"\033[38;2;224;150;40m", // gold-orange
-	"\033[38;2;234;130;36m", // orange
-	"\033[38;2;244;110;32m", // orange-red
-	"\033[38;2;244;80;30m",  // red-orange
-	"\033[38;2;244;67;54m",  // red
-}
-
 // generateProgressBar 生成進度條，根據 RenderMode 選擇渲染方式
 func generateProgressBar(percentage int) string {
 	switch RenderMode {
@@ -288,7 +274,7 @@ func generateProgressBarTrueColor(percentage int) string {
 
 	// 填充部分：每格獨立漸層色
 	for i := 0; i < filled; i++ {
-		bar.WriteString(gradientColors[i])
+		bar.WriteString(statusline.ColorGradient[i])
 		bar.WriteString("█")
 	}
 	if filled > 0 {
@@ -10,31 +10,88 @@ import (
 	"time"
 )
 
-// 簡單快取
+// branchCacheTTL 分支快取的有效時間
+const branchCacheTTL = 5 * time.Second
+
+// branchEntry 單一倉庫的分支快取
+type branchEntry struct {
+	value   string
+	expires time.Time
+}
+
+// 以倉庫根目錄為 key 的快取，避免長駐程式（daemon、測試、多 repo 工具）
+// 在 5 秒內拿到另一個倉庫的分支
 var (
-	branchCache   string
-	branchExpires time.Time
-	cacheMutex    sync.RWMutex
+	branchCache = make(map[string]branchEntry)
+	cacheMutex  sync.RWMutex
 )
 
 // ClearCache 清除快取（用於測試）
 func ClearCache() {
 	cacheMutex.Lock()
 	defer cacheMutex.Unlock()
-	branchCache = ""
-	branchExpires = time.Time{}
+	branchCache = make(map[string]branchEntry)
 }
 
-// GetBranch 獲取 Git 分支（帶快取）
+// GetBranch 獲取 Git 分支（帶快取，依倉庫根目錄分開）
 func GetBranch(dir string) string {
+	key := CacheKey(dir)
+
 	cacheMutex.RLock()
-	if time.Now().Before(branchExpires) && branchCache != "" {
-		result := branchCache
+	if entry, ok := branchCache[key]; ok && time.Now().Before(entry.expires) && entry.value != "" {
 		cacheMutex.RUnlock()
-		return result
+		return entry.value
 	}
 	cacheMutex.RUnlock()
 
+	// 優先直接讀取 .git 檔案；無法解析時（reftable、GIT_DIR 等特殊配置）才啟動 git 行程
+	result, ok := branchFromFiles(dir)
+	if !ok {
+		result = branchFromCLI(dir)
+	}
+	if result == "" {
+		return ""
+	}
+
+	// 更新快取
+	cacheMutex.Lock()
+	branchCache[key] = branchEntry{value: result, expires: time.Now().Add(branchCacheTTL)}
+	cacheMutex.Unlock()
+
+	return result
+}
+
+// branchFromFiles 以 Repo reader 解析分支與 worktree 狀態，不啟動任何 git 行程。
+// 第二個回傳值為 false 表示無法僅靠檔案判斷，呼叫端應退回 branchFromCLI。
+func branchFromFiles(dir string) (string, bool) {
+	repo := Open(dir)
+	if repo == nil {
+		return "", false
+	}
+	ref, sha, err := repo.Head()
+	if err != nil {
+		return "", false
+	}
+
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	if ref == "" {
+		// detached HEAD：tag 名稱優先，否則短 SHA（同 detachedLabel）
+		if tag := repo.TagAt(sha); tag != "" {
+			branch = "(" + tag + ")"
+		} else {
+			branch = "(" + sha[:7] + ")"
+		}
+	}
+
+	icon, label := "⚡", ""
+	if repo.IsWorktree() {
+		icon, label = "🔀", " (wt)"
+	}
+	return formatBranch(icon, branch, label), true
+}
+
+// branchFromCLI 以 git 指令取得分支（reader 無法解析時的 fallback）
+func branchFromCLI(dir string) string {
 	// 檢查是否為 Git 倉庫
 	gitPath := ".git"
 	if dir != "" {
@@ -57,7 +114,11 @@ func GetBranch(dir string) string {
 
 	branch := strings.TrimSpace(string(output))
 	if branch == "" {
-		return ""
+		// detached HEAD（rebase/bisect 進行中或直接 checkout commit）
+		branch = detachedLabel(dir)
+		if branch == "" {
+			return ""
+		}
 	}
 
 	// 檢測是否在 worktree 中
@@ -86,15 +147,7 @@ func GetBranch(dir string) string {
 		}
 	}
 
-	result := formatBranch(icon, branch, worktreeLabel)
-
-	// 更新快取
-	cacheMutex.Lock()
-	branchCache = result
-	branchExpires = time.Now().Add(5 * time.Second)
-	cacheMutex.Unlock()
-
-	return result
+	return formatBranch(icon, branch, worktreeLabel)
 }
 
 // FormatWorktreeBranch 格式化結構化 worktree 資料的分支顯示。
@@ -111,6 +164,26 @@ func FormatWorktreeBranch(name, branch string) string {
 	return formatBranch("🔀", branch, label)
 }
 
+// detachedLabel 回傳 detached HEAD 的顯示標籤：HEAD 正好在 tag 上時顯示 tag 名稱，
+// 否則顯示短 SHA，兩者皆以括號包住以與分支名區分（同 git-prompt.sh）。
+// 無法解析 HEAD（例如尚無 commit）時回傳空字串。
+func detachedLabel(dir string) string {
+	if out, err := exec.Command("git", "-C", dir, "describe", "--tags", "--exact-match", "HEAD").Output(); err == nil {
+		if tag := strings.TrimSpace(string(out)); tag != "" {
+			return "(" + tag + ")"
+		}
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	sha := strings.TrimSpace(string(out))
+	if sha == "" {
+		return ""
+	}
+	return "(" + sha + ")"
+}
+
 // formatBranch 統一的分支格式化內部函式
 func formatBranch(icon, branch, label string) string {
 	return fmt.Sprintf(" %s %s%s", icon, branch, label)
@@ -282,3 +282,85 @@ func runGitCommand(t *testing.T, dir string, args ...string) {
 		return
 	}
 }
+
+func TestGetBranch_CacheKeyedByRepo(t *testing.T) {
+	ClearCache()
+
+	repoA := t.TempDir()
+	repoB := t.TempDir()
+	for _, dir := range []string{repoA, repoB} {
+		runGitCommand(t, dir, "init")
+		runGitCommand(t, dir, "config", "user.name", "Test User")
+		runGitCommand(t, dir, "config", "user.email", "test@example.com")
+		runGitCommand(t, dir, "commit", "--allow-empty", "-m", "Initial commit")
+	}
+	runGitCommand(t, repoA, "checkout", "-b", "branch-a")
+	runGitCommand(t, repoB, "checkout", "-b", "branch-b")
+
+	// 不清快取、連續查詢兩個倉庫：第二次查詢不應拿到第一個倉庫的快取
+	if result := GetBranch(repoA); !strings.Contains(result, "branch-a") {
+		t.Fatalf("expected branch-a for repo A, got: %s", result)
+	}
+	if result := GetBranch(repoB); !strings.Contains(result, "branch-b") {
+		t.Fatalf("expected branch-b for repo B, got: %s", result)
+	}
+
+	// 子目錄與根目錄共用同一筆快取
+	sub := filepath.Join(repoA, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if result := GetBranch(sub); !strings.Contains(result, "branch-a") {
+		t.Fatalf("expected branch-a for repo A subdirectory, got: %s", result)
+	}
+}
+
+func TestRepoRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	if resolvedPath, err := filepath.EvalSymlinks(tmpDir); err == nil {
+		tmpDir = resolvedPath
+	}
+	runGitCommand(t, tmpDir, "init")
+
+	nested := filepath.Join(tmpDir, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if root := RepoRoot(nested); root != tmpDir {
+		t.Errorf("RepoRoot(%q) = %q, want %q", nested, root, tmpDir)
+	}
+	if gitDir := GitDir(tmpDir); gitDir != filepath.Join(tmpDir, ".git") {
+		t.Errorf("GitDir(%q) = %q, want %q", tmpDir, gitDir, filepath.Join(tmpDir, ".git"))
+	}
+}
+
+func TestGetBranch_DetachedHead(t *testing.T) {
+	ClearCache()
+
+	tmpDir := t.TempDir()
+	runGitCommand(t, tmpDir, "init")
+	runGitCommand(t, tmpDir, "config", "user.name", "Test User")
+	runGitCommand(t, tmpDir, "config", "user.email", "test@example.com")
+	runGitCommand(t, tmpDir, "commit", "--allow-empty", "-m", "first")
+	runGitCommand(t, tmpDir, "tag", "v1.0.0")
+	runGitCommand(t, tmpDir, "commit", "--allow-empty", "-m", "second")
+
+	// detached 在 tag 上：顯示 tag 名稱
+	runGitCommand(t, tmpDir, "checkout", "--detach", "v1.0.0")
+	if result := GetBranch(tmpDir); !strings.Contains(result, "(v1.0.0)") {
+		t.Errorf("expected tag name for detached HEAD at tag, got: %q", result)
+	}
+
+	// detached 在無 tag 的 commit 上：顯示短 SHA
+	ClearCache()
+	runGitCommand(t, tmpDir, "checkout", "--detach", "HEAD@{1}")
+	out, err := exec.Command("git", "-C", tmpDir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := strings.TrimSpace(string(out))
+	if result := GetBranch(tmpDir); !strings.Contains(result, "("+sha+")") {
+		t.Errorf("expected short SHA %q for detached HEAD, got: %q", sha, result)
+	}
+}
@@ -0,0 +1,381 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repo 直接讀取 .git 目錄的輕量 reader，不啟動 git 行程。
+// 只處理 files 格式的 ref 儲存（loose refs + packed-refs）；遇到無法解析的情況
+// （例如 reftable、packfile 中的 object）由呼叫端退回 git CLI。
+type Repo struct {
+	Root      string // 工作目錄根目錄
+	GitDir    string // 此 worktree 的 git 目錄（HEAD、index、rebase-merge 等所在）
+	CommonDir string // 共用的 git 目錄（refs、packed-refs、config、objects 所在）
+}
+
+// ErrNotResolved 表示 reader 無法僅靠檔案解析出結果
+var ErrNotResolved = errors.New("git: ref not resolvable from files")
+
+// Open 開啟 dir 所屬的倉庫。非 git 目錄或 .git 無法解析時回傳 nil。
+func Open(dir string) *Repo {
+	root := RepoRoot(dir)
+	if root == "" {
+		return nil
+	}
+	gitDir := GitDir(root)
+	if gitDir == "" {
+		return nil
+	}
+	return &Repo{Root: root, GitDir: gitDir, CommonDir: commonDir(gitDir)}
+}
+
+// commonDir 解析 worktree git 目錄中的 commondir 檔案；不存在時即為 gitDir 本身
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	return resolvePath(gitDir, strings.TrimSpace(string(data)))
+}
+
+// IsWorktree 回報是否為 linked worktree（git-dir 與 git-common-dir 不同）
+func (r *Repo) IsWorktree() bool {
+	return filepath.Clean(r.GitDir) != filepath.Clean(r.CommonDir)
+}
+
+// Head 讀取 HEAD。symbolic ref 時回傳 ref 名稱（如 "refs/heads/main"）；
+// detached 時 ref 為空字串。sha 為 HEAD 指向的 commit，unborn branch 時為空字串。
+func (r *Repo) Head() (ref, sha string, err error) {
+	data, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", "", err
+	}
+	content := strings.TrimSpace(string(data))
+
+	if target, ok := strings.CutPrefix(content, "ref: "); ok {
+		// reftable 格式的 HEAD 固定指向 refs/heads/.invalid，無法從檔案解析
+		if target == "refs/heads/.invalid" {
+			return "", "", ErrNotResolved
+		}
+		sha, err := r.ResolveRef(target)
+		if errors.Is(err, os.ErrNotExist) {
+			return target, "", nil // unborn branch
+		}
+		return target, sha, err
+	}
+
+	if !isSHA(content) {
+		return "", "", ErrNotResolved
+	}
+	return "", content, nil
+}
+
+// Branch 回傳目前分支的短名稱；detached HEAD 時回傳空字串
+func (r *Repo) Branch() (string, error) {
+	ref, _, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(ref, "refs/heads/"), nil
+}
+
+// ResolveRef 將完整 ref 名稱解析為 commit sha（最多跟隨 5 層 symbolic ref）。
+// 查找順序：per-worktree git 目錄、common 目錄的 loose ref、packed-refs。
+// ref 不存在時回傳 os.ErrNotExist。
+func (r *Repo) ResolveRef(name string) (string, error) {
+	for depth := 0; depth < 5; depth++ {
+		value, err := r.readRef(name)
+		if err != nil {
+			return "", err
+		}
+		target, ok := strings.CutPrefix(value, "ref: ")
+		if !ok {
+			if !isSHA(value) {
+				return "", ErrNotResolved
+			}
+			return value, nil
+		}
+		name = target
+	}
+	return "", ErrNotResolved
+}
+
+// readRef 讀取單一 ref 的原始內容（sha 或 "ref: ..."）
+func (r *Repo) readRef(name string) (string, error) {
+	for _, dir := range []string{r.GitDir, r.CommonDir} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	refs, err := r.packedRefs()
+	if err != nil {
+		return "", err
+	}
+	if ref, ok := refs[name]; ok {
+		return ref.sha, nil
+	}
+	return "", os.ErrNotExist
+}
+
+// packedRef 為 packed-refs 中的一筆資料；peeled 為 annotated tag 指向的 commit
+type packedRef struct {
+	sha    string
+	peeled string
+}
+
+// packedRefs 解析 common 目錄中的 packed-refs。檔案不存在時回傳空 map。
+func (r *Repo) packedRefs() (map[string]packedRef, error) {
+	refs := make(map[string]packedRef)
+	file, err := os.Open(filepath.Join(r.CommonDir, "packed-refs"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return refs, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var last string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "^"):
+			// peeled 行緊接在 annotated tag 之後
+			if ref, ok := refs[last]; ok {
+				ref.peeled = line[1:]
+				refs[last] = ref
+			}
+		default:
+			sha, name, ok := strings.Cut(line, " ")
+			if !ok {
+				continue
+			}
+			refs[name] = packedRef{sha: sha}
+			last = name
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// Upstream 回傳目前分支設定的 upstream ref（如 "refs/remotes/origin/main"）。
+// 依 config 中 [branch "<name>"] 的 remote 與 merge 推導；remote 為 "." 時為本地分支。
+// 未設定 upstream 或 detached HEAD 時回傳空字串。
+func (r *Repo) Upstream() string {
+	branch, err := r.Branch()
+	if err != nil || branch == "" {
+		return ""
+	}
+	remote, merge := r.BranchConfig(branch, "remote"), r.BranchConfig(branch, "merge")
+	if remote == "" || merge == "" {
+		return ""
+	}
+	if remote == "." {
+		return merge
+	}
+	return "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// BranchConfig 回傳 config 中 [branch "<name>"] 的指定 key，例如 "remote"、"merge"
+func (r *Repo) BranchConfig(branch, key string) string {
+	return r.configValue(`branch "`+branch+`"`, key)
+}
+
+// RemoteURL 讀取 remote.<name>.url，未設定時回傳空字串
+func (r *Repo) RemoteURL(name string) string {
+	if name == "" {
+		return ""
+	}
+	return r.configValue(`remote "`+name+`"`, "url")
+}
+
+// configValue 以簡化的 INI 解析讀取 common 目錄 config 中 section 的 key。
+// 只支援 git 實際寫入的 [section "subsection"] 與 key = value 格式，不處理 include。
+func (r *Repo) configValue(section, key string) string {
+	file, err := os.Open(filepath.Join(r.CommonDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	var current, value string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), key) {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(v), `"`) // 後出現的設定覆蓋前者，與 git 相同
+	}
+	return value
+}
+
+// TagAt 回傳指向 sha 的 tag 名稱（含 annotated tag 的 peeled 結果）；找不到時回傳空字串。
+// 多個 tag 指向同一 commit 時取字典序最小者，結果穩定。
+func (r *Repo) TagAt(sha string) string {
+	best := ""
+	consider := func(name string) {
+		if best == "" || name < best {
+			best = name
+		}
+	}
+
+	tagsDir := filepath.Join(r.CommonDir, "refs", "tags")
+	_ = filepath.WalkDir(tagsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		target := strings.TrimSpace(string(data))
+		if target != sha {
+			// annotated tag：loose tag object 內記錄其指向的 object
+			if obj, err := r.readLooseObject(target); err != nil || obj.kind != "tag" || obj.header("object") != sha {
+				return nil
+			}
+		}
+		if rel, err := filepath.Rel(tagsDir, path); err == nil {
+			consider(filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	if refs, err := r.packedRefs(); err == nil {
+		for name, ref := range refs {
+			tag, ok := strings.CutPrefix(name, "refs/tags/")
+			if ok && (ref.sha == sha || ref.peeled == sha) {
+				consider(tag)
+			}
+		}
+	}
+	return best
+}
+
+// Commit 為從 loose object 讀出的 commit 摘要
+type Commit struct {
+	Time    time.Time // committer 時間
+	Subject string    // commit 訊息第一行
+}
+
+// ReadCommit 讀取 loose commit object。object 已被打包進 packfile 時回傳 ErrNotResolved。
+func (r *Repo) ReadCommit(sha string) (*Commit, error) {
+	obj, err := r.readLooseObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if obj.kind != "commit" {
+		return nil, ErrNotResolved
+	}
+
+	commit := &Commit{}
+	committer := strings.Fields(obj.header("committer"))
+	// committer 格式：Name <email> <unix-seconds> <tz>
+	if len(committer) >= 2 {
+		if sec, err := strconv.ParseInt(committer[len(committer)-2], 10, 64); err == nil {
+			commit.Time = time.Unix(sec, 0)
+		}
+	}
+	subject, _, _ := strings.Cut(obj.message, "\n")
+	commit.Subject = strings.TrimSpace(subject)
+	return commit, nil
+}
+
+// StashCount 由 logs/refs/stash 的 reflog 行數計算 stash 數量
+func (r *Repo) StashCount() int {
+	data, err := os.ReadFile(filepath.Join(r.CommonDir, "logs", "refs", "stash"))
+	if err != nil {
+		return 0
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+// looseObject 為解壓後的 commit/tag object
+type looseObject struct {
+	kind    string
+	headers string // 空行之前的 header 區段
+	message string // 空行之後的訊息
+}
+
+// header 回傳第一個符合 key 的 header 值
+func (o *looseObject) header(key string) string {
+	for _, line := range strings.Split(o.headers, "\n") {
+		if v, ok := strings.CutPrefix(line, key+" "); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// maxLooseObjectSize 讀取 loose object 的上限（commit/tag object 遠小於此值）
+const maxLooseObjectSize = 1 << 20
+
+// readLooseObject 解壓 objects/xx/yyyy。不存在（已打包）時回傳 ErrNotResolved。
+func (r *Repo) readLooseObject(sha string) (*looseObject, error) {
+	if !isSHA(sha) {
+		return nil, ErrNotResolved
+	}
+	file, err := os.Open(filepath.Join(r.CommonDir, "objects", sha[:2], sha[2:]))
+	if err != nil {
+		return nil, ErrNotResolved
+	}
+	defer func() { _ = file.Close() }()
+
+	zr, err := zlib.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(zr, maxLooseObjectSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// 格式：<kind> <size>\x00<content>
+	header, content, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return nil, ErrNotResolved
+	}
+	kind, _, _ := strings.Cut(string(header), " ")
+	headers, message, _ := strings.Cut(string(content), "\n\n")
+	return &looseObject{kind: kind, headers: headers, message: message}, nil
+}
+
+// isSHA 判斷是否為 40（SHA-1）或 64（SHA-256）字元的小寫十六進位 object id
+func isSHA(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// initTestRepo 建立在 main 分支上、含一個 commit 的臨時倉庫
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if resolvedPath, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolvedPath
+	}
+	runGitCommand(t, dir, "init")
+	runGitCommand(t, dir, "symbolic-ref", "HEAD", "refs/heads/main")
+	runGitCommand(t, dir, "config", "user.name", "Test User")
+	runGitCommand(t, dir, "config", "user.email", "test@example.com")
+	runGitCommand(t, dir, "commit", "--allow-empty", "-m", "Initial commit")
+	return dir
+}
+
+// gitOutput 執行 git 並回傳去除空白的 stdout
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRepoHead(t *testing.T) {
+	dir := initTestRepo(t)
+	repo := Open(dir)
+	if repo == nil {
+		t.Fatal("expected repo to open")
+	}
+
+	ref, sha, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "refs/heads/main" {
+		t.Errorf("expected ref refs/heads/main, got %q", ref)
+	}
+	if want := gitOutput(t, dir, "rev-parse", "HEAD"); sha != want {
+		t.Errorf("expected sha %q, got %q", want, sha)
+	}
+
+	// packed-refs：loose ref 被打包後仍能解析
+	runGitCommand(t, dir, "pack-refs", "--all")
+	if _, err := os.Stat(filepath.Join(dir, ".git", "refs", "heads", "main")); err == nil {
+		t.Fatal("expected loose ref to be removed by pack-refs")
+	}
+	if _, packedSHA, err := repo.Head(); err != nil || packedSHA != sha {
+		t.Errorf("expected packed sha %q, got %q (err %v)", sha, packedSHA, err)
+	}
+
+	// detached HEAD
+	runGitCommand(t, dir, "checkout", "--detach")
+	ref, detachedSHA, err := repo.Head()
+	if err != nil || ref != "" || detachedSHA != sha {
+		t.Errorf("expected detached HEAD at %q, got ref=%q sha=%q err=%v", sha, ref, detachedSHA, err)
+	}
+}
+
+func TestRepoUnbornBranch(t *testing.T) {
+	dir := t.TempDir()
+	runGitCommand(t, dir, "init")
+	runGitCommand(t, dir, "symbolic-ref", "HEAD", "refs/heads/trunk")
+
+	repo := Open(dir)
+	branch, err := repo.Branch()
+	if err != nil || branch != "trunk" {
+		t.Errorf("expected unborn branch trunk, got %q (err %v)", branch, err)
+	}
+}
+
+func TestRepoWorktree(t *testing.T) {
+	dir := initTestRepo(t)
+	wtDir := t.TempDir()
+	if resolvedPath, err := filepath.EvalSymlinks(wtDir); err == nil {
+		wtDir = resolvedPath
+	}
+	wtPath := filepath.Join(wtDir, "wt")
+	runGitCommand(t, dir, "worktree", "add", "-b", "wt-branch", wtPath)
+
+	repo := Open(wtPath)
+	if repo == nil {
+		t.Fatal("expected worktree to open")
+	}
+	if !repo.IsWorktree() {
+		t.Error("expected IsWorktree for linked worktree")
+	}
+	if repo.CommonDir != filepath.Join(dir, ".git") {
+		t.Errorf("expected common dir %q, got %q", filepath.Join(dir, ".git"), repo.CommonDir)
+	}
+	if branch, _ := repo.Branch(); branch != "wt-branch" {
+		t.Errorf("expected wt-branch, got %q", branch)
+	}
+
+	if main := Open(dir); main.IsWorktree() {
+		t.Error("main repo should not be a worktree")
+	}
+}
+
+func TestRepoUpstream(t *testing.T) {
+	dir := initTestRepo(t)
+	runGitCommand(t, dir, "checkout", "-b", "feature")
+	runGitCommand(t, dir, "branch", "--set-upstream-to", "main")
+
+	repo := Open(dir)
+	if up := repo.Upstream(); up != "refs/heads/main" {
+		t.Errorf("expected local upstream refs/heads/main, got %q", up)
+	}
+
+	runGitCommand(t, dir, "config", "branch.feature.remote", "origin")
+	runGitCommand(t, dir, "config", "branch.feature.merge", "refs/heads/feature")
+	if up := repo.Upstream(); up != "refs/remotes/origin/feature" {
+		t.Errorf("expected refs/remotes/origin/feature, got %q", up)
+	}
+
+	runGitCommand(t, dir, "checkout", "main")
+	if up := repo.Upstream(); up != "" {
+		t.Errorf("expected no upstream for main, got %q", up)
+	}
+}
+
+func TestRepoTagAt(t *testing.T) {
+	dir := initTestRepo(t)
+	repo := Open(dir)
+	sha := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	if tag := repo.TagAt(sha); tag != "" {
+		t.Fatalf("expected no tag, got %q", tag)
+	}
+
+	// annotated tag（loose tag object 需解壓才能得知指向的 commit）
+	runGitCommand(t, dir, "tag", "-a", "v2.0.0", "-m", "release")
+	if tag := repo.TagAt(sha); tag != "v2.0.0" {
+		t.Errorf("expected annotated tag v2.0.0, got %q", tag)
+	}
+
+	// 多個 tag 取字典序最小者；打包後以 peeled 行比對
+	runGitCommand(t, dir, "tag", "v1.0.0")
+	runGitCommand(t, dir, "pack-refs", "--all")
+	if tag := repo.TagAt(sha); tag != "v1.0.0" {
+		t.Errorf("expected v1.0.0 from packed-refs, got %q", tag)
+	}
+}
+
+func TestRepoReadCommit(t *testing.T) {
+	dir := initTestRepo(t)
+	runGitCommand(t, dir, "commit", "--allow-empty", "-m", "second commit\n\nbody text")
+	repo := Open(dir)
+	_, sha, _ := repo.Head()
+
+	commit, err := repo.ReadCommit(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Subject != "second commit" {
+		t.Errorf("expected subject %q, got %q", "second commit", commit.Subject)
+	}
+	if want := gitOutput(t, dir, "log", "-1", "--format=%ct"); strconv.FormatInt(commit.Time.Unix(), 10) != want {
+		t.Errorf("expected commit time %s, got %d", want, commit.Time.Unix())
+	}
+
+	// 打包後 loose object 消失，應回報無法解析讓呼叫端退回 CLI
+	runGitCommand(t, dir, "gc", "--quiet")
+	if _, err := repo.ReadCommit(sha); !errors.Is(err, ErrNotResolved) {
+		t.Errorf("expected ErrNotResolved for packed object, got %v", err)
+	}
+}
+
+func TestRepoStashCount(t *testing.T) {
+	dir := initTestRepo(t)
+	repo := Open(dir)
+	if n := repo.StashCount(); n != 0 {
+		t.Fatalf("expected 0 stashes, got %d", n)
+	}
+
+	file := filepath.Join(dir, "f.txt")
+	for i, content := range []string{"a", "b"} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGitCommand(t, dir, "add", "f.txt")
+		runGitCommand(t, dir, "stash")
+		if n := repo.StashCount(); n != i+1 {
+			t.Errorf("expected %d stashes, got %d", i+1, n)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepoRoot 從 dir 往上尋找含 .git（目錄或 worktree 的 gitdir 檔案）的倉庫根目錄。
+// 純檔案系統操作，不啟動 git 行程；找不到時回傳空字串。
+// dir 為空字串時以目前工作目錄為起點。
+func RepoRoot(dir string) string {
+	start := dir
+	if start == "" {
+		start = "."
+	}
+	abs, err := filepath.Abs(start)
+	if err != nil {
+		return ""
+	}
+
+	for cur := abs; ; {
+		if _, err := os.Stat(filepath.Join(cur, ".git")); err == nil {
+			return cur
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return ""
+		}
+		cur = parent
+	}
+}
+
+// GitDir 回傳倉庫根目錄對應的 git 目錄。
+// 一般倉庫為 <root>/.git；worktree 與 submodule 的 .git 是內容為
+// "gitdir: <path>" 的檔案，需解析出實際路徑（相對路徑以 root 為基準）。
+func GitDir(root string) string {
+	if root == "" {
+		return ""
+	}
+	dotGit := filepath.Join(root, ".git")
+	fi, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if fi.IsDir() {
+		return dotGit
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, "gitdir:") {
+		return ""
+	}
+	return resolvePath(root, strings.TrimSpace(strings.TrimPrefix(content, "gitdir:")))
+}
+
+// CacheKey 回傳 dir 所屬倉庫的快取 key（倉庫根目錄），供 git 與 gitstatus 的快取共用。
+// 非 git 目錄回傳 dir 的絕對路徑，避免不同目錄共用同一筆快取。
+func CacheKey(dir string) string {
+	if root := RepoRoot(dir); root != "" {
+		return root
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
@@ -0,0 +1,155 @@
+package gitstatus
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/git"
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+)
+
+// Details 控制 FormatDetails 要顯示哪些選用子欄位
+type Details struct {
+	Stash      bool // ⚑N stash 數量
+	DiffStat   bool // Δ+N/-M working tree 相對 HEAD 的行數變化
+	LastCommit bool // HEAD commit 的時間與標題
+}
+
+// key 子欄位組合的快取 key（如 "s-c"）
+func (d Details) key() string {
+	flag := func(on bool, c string) string {
+		if on {
+			return c
+		}
+		return "-"
+	}
+	return flag(d.Stash, "s") + flag(d.DiffStat, "d") + flag(d.LastCommit, "c")
+}
+
+// maxSubjectLen commit 標題顯示的最大字元數
+const maxSubjectLen = 24
+
+// parseStash 取得 stash 數量（沒有 stash 時 refs/stash 不存在，維持 0）。
+// reader 可用時直接計算 stash reflog 行數。
+func parseStash(ctx context.Context, repo *git.Repo, dir string, info *GitStatusInfo) {
+	if repo != nil {
+		info.Stashes = repo.StashCount()
+		return
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-list", "--walk-reflogs", "--count", "refs/stash")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil {
+		info.Stashes = n
+	}
+}
+
+// parseLastCommit 取得 HEAD commit 的 committer 時間與標題。
+// HEAD commit 仍是 loose object（剛 commit 的常見情況）時直接解壓讀取，否則退回 git log。
+func parseLastCommit(ctx context.Context, repo *git.Repo, dir string, info *GitStatusInfo) {
+	if repo != nil {
+		if _, sha, err := repo.Head(); err == nil && sha != "" {
+			if commit, err := repo.ReadCommit(sha); err == nil {
+				info.LastCommitTime = commit.Time
+				info.LastCommitSubject = commit.Subject
+				return
+			}
+		}
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "log", "-1", "--format=%ct%x00%s")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	ts, subject, ok := strings.Cut(strings.TrimRight(string(output), "\n"), "\x00")
+	if !ok {
+		return
+	}
+	if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		info.LastCommitTime = time.Unix(sec, 0)
+		info.LastCommitSubject = subject
+	}
+}
+
+// parseDiffStat 取得 working tree（含 staged）相對 HEAD 的行數變化。
+// 與 Cost.TotalLinesAdded 不同：後者只計 Claude 的編輯，這裡是整個工作區。
+func parseDiffStat(ctx context.Context, _ *git.Repo, dir string, info *GitStatusInfo) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "diff", "--shortstat", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	info.Insertions, info.Deletions = parseShortstat(string(output))
+}
+
+// parseShortstat 解析 " 3 files changed, 12 insertions(+), 3 deletions(-)"
+func parseShortstat(s string) (insertions, deletions int) {
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		fields := strings.Fields(part)
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(fields[1], "insertion"):
+			insertions = n
+		case strings.HasPrefix(fields[1], "deletion"):
+			deletions = n
+		}
+	}
+	return insertions, deletions
+}
+
+// FormatDetails 格式化選用子欄位（stash、行數變化、最後 commit），
+// 接在 Format 的結果之後顯示；沒有可顯示的內容時回傳空字串。
+func FormatDetails(info *GitStatusInfo, d Details) string {
+	if info == nil {
+		return ""
+	}
+
+	var parts []string
+	if d.Stash && info.Stashes > 0 {
+		parts = append(parts, fmt.Sprintf("⚑%d", info.Stashes))
+	}
+	if d.DiffStat && (info.Insertions > 0 || info.Deletions > 0) {
+		parts = append(parts, fmt.Sprintf("Δ+%d/-%d", info.Insertions, info.Deletions))
+	}
+	if d.LastCommit && !info.LastCommitTime.IsZero() {
+		commit := formatAge(time.Since(info.LastCommitTime))
+		if info.LastCommitSubject != "" {
+			commit += " " + statusline.Truncate(info.LastCommitSubject, maxSubjectLen, "…")
+		}
+		parts = append(parts, commit)
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// formatAge 將經過時間格式化為精簡字串（如 45s / 12m / 3h / 5d）
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		if d < 0 {
+			d = 0
+		}
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
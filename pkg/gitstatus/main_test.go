@@ -0,0 +1,18 @@
+package gitstatus
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain 清除繼承自外部環境的 GIT_* 變數，確保測試隔離（同 pkg/git/main_test.go）。
+func TestMain(m *testing.M) {
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(key, "GIT_") {
+			_ = os.Unsetenv(key)
+		}
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,102 @@
+package gitstatus
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 進行中的倉庫操作（顯示字串沿用 git-prompt.sh 的慣例）
+const (
+	OpRebase     = "REBASE"
+	OpAM         = "AM"
+	OpMerging    = "MERGING"
+	OpCherryPick = "CHERRY-PICKING"
+	OpReverting  = "REVERTING"
+	OpBisecting  = "BISECTING"
+)
+
+// Operation 代表倉庫中進行到一半的操作（rebase、merge、cherry-pick…）
+type Operation struct {
+	Name  string // 操作名稱，如 "REBASE"、"MERGING"
+	Step  int    // 目前步驟（僅 rebase/am 有值）
+	Total int    // 總步驟數（僅 rebase/am 有值）
+}
+
+// String 格式化為 "REBASE 3/7" 或 "MERGING"
+func (o Operation) String() string {
+	if o.Name == "" {
+		return ""
+	}
+	if o.Step > 0 && o.Total > 0 {
+		return o.Name + " " + strconv.Itoa(o.Step) + "/" + strconv.Itoa(o.Total)
+	}
+	return o.Name
+}
+
+// detectOperation 依 git 目錄中的狀態檔判斷進行中的操作。
+// 判斷順序與 git-prompt.sh 相同：rebase/am 優先，其次 merge、cherry-pick、revert、bisect。
+// worktree 的狀態檔位於各自的 git 目錄（非 common dir），因此 gitDir 應為 git.GitDir 的結果。
+func detectOperation(gitDir string) Operation {
+	if gitDir == "" {
+		return Operation{}
+	}
+
+	if dir := filepath.Join(gitDir, "rebase-merge"); isDir(dir) {
+		return Operation{
+			Name:  OpRebase,
+			Step:  readInt(filepath.Join(dir, "msgnum")),
+			Total: readInt(filepath.Join(dir, "end")),
+		}
+	}
+
+	if dir := filepath.Join(gitDir, "rebase-apply"); isDir(dir) {
+		// rebase-apply 同時用於 apply 型 rebase 與 git am，以 applying 標記區分
+		name := OpRebase
+		if exists(filepath.Join(dir, "applying")) {
+			name = OpAM
+		}
+		return Operation{
+			Name:  name,
+			Step:  readInt(filepath.Join(dir, "next")),
+			Total: readInt(filepath.Join(dir, "last")),
+		}
+	}
+
+	switch {
+	case exists(filepath.Join(gitDir, "MERGE_HEAD")):
+		return Operation{Name: OpMerging}
+	case exists(filepath.Join(gitDir, "CHERRY_PICK_HEAD")):
+		return Operation{Name: OpCherryPick}
+	case exists(filepath.Join(gitDir, "REVERT_HEAD")):
+		return Operation{Name: OpReverting}
+	case exists(filepath.Join(gitDir, "BISECT_LOG")):
+		return Operation{Name: OpBisecting}
+	}
+
+	return Operation{}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// readInt 讀取只含一個整數的狀態檔（如 rebase-merge/msgnum），失敗時回傳 0
+func readInt(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -2,12 +2,19 @@ package gitstatus
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/git"
 )
 
 // GitStatusInfo 增強型 Git 狀態資訊
@@ -19,71 +26,234 @@ type GitStatusInfo struct {
 	Added     int
 	Deleted   int
 	Untracked int
+	Conflicts int       // unmerged 項目（UU/AA/DU/UD/AU/UA/DD）
+	Staged    int       // index 有變更的項目（porcelain X 欄）
+	Unstaged  int       // working tree 有變更的項目（porcelain Y 欄，不含 untracked）
+	Operation Operation // 進行中的 rebase/merge/cherry-pick/bisect
+
+	// 選用子欄位（見 FormatDetails）
+	Stashes           int       // stash 數量
+	LastCommitTime    time.Time // HEAD commit 的 committer 時間
+	LastCommitSubject string    // HEAD commit 標題
+	Insertions        int       // git diff --shortstat HEAD 的新增行數
+	Deletions         int       // git diff --shortstat HEAD 的刪除行數
 }
 
-// 快取
+// statusCacheTTL 記憶體與檔案快取的有效時間
+const statusCacheTTL = 5 * time.Second
+
+// statusEntry 單一倉庫的狀態快取
+type statusEntry struct {
+	info    *GitStatusInfo
+	expires time.Time
+}
+
+// 以倉庫根目錄為 key 的記憶體快取
 var (
-	statusCache   *GitStatusInfo
-	statusExpires time.Time
-	statusMutex   sync.RWMutex
+	statusCache = make(map[string]statusEntry)
+	statusMutex sync.RWMutex
 )
 
+// cachedStatus 用於檔案快取。State 由 HEAD 與 index mtime 組成，
+// 任一改變（commit、checkout、stage）即視為失效。
+type cachedStatus struct {
+	Root      string        `json:"root"`
+	State     string        `json:"state"`
+	Info      GitStatusInfo `json:"info"`
+	ExpiresAt int64         `json:"expires_at"`
+}
+
 // ClearCache 清除快取（用於測試）
 func ClearCache() {
 	statusMutex.Lock()
 	defer statusMutex.Unlock()
-	statusCache = nil
-	statusExpires = time.Time{}
+	statusCache = make(map[string]statusEntry)
 }
 
-// Get 取得增強型 Git 狀態（帶 5 秒快取）
-func Get(dir string) *GitStatusInfo {
+// Get 取得增強型 Git 狀態（帶 5 秒快取，依倉庫根目錄分開）。
+// d 指定要取得的選用子欄位，未啟用的不會啟動對應的 git 指令。
+// 記憶體快取之外另有檔案快取（key 為 root + HEAD + index mtime + 子欄位），
+// 讓各自獨立的短命 statusline 行程在倉庫未變動時略過 git status。
+func Get(dir string, d Details) *GitStatusInfo {
+	root := git.CacheKey(dir)
+	key := root + "|" + d.key()
+
 	statusMutex.RLock()
-	if time.Now().Before(statusExpires) && statusCache != nil {
-		result := statusCache
+	if entry, ok := statusCache[key]; ok && time.Now().Before(entry.expires) && entry.info != nil {
 		statusMutex.RUnlock()
-		return result
+		return entry.info
 	}
 	statusMutex.RUnlock()
 
+	repo := git.Open(dir)
+	state := cacheState(repo, d)
+	if cached := loadFileCache(root, state); cached != nil {
+		updateMemoryCache(key, cached)
+		return cached
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
 	info := &GitStatusInfo{}
+	if repo != nil {
+		info.Operation = detectOperation(repo.GitDir)
+	}
 
-	// 並行取得 porcelain 和 ahead/behind
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		parsePorcelain(ctx, dir, info)
-	}()
+	// 並行取得 porcelain、ahead/behind 與啟用的選用子欄位，共用同一個 1 秒 context。
+	// 每個 goroutine 只寫入 info 中各自負責的欄位，不需額外加鎖。
+	fetchers := []fetcher{parsePorcelain, parseAheadBehind}
+	if d.Stash {
+		fetchers = append(fetchers, parseStash)
+	}
+	if d.LastCommit {
+		fetchers = append(fetchers, parseLastCommit)
+	}
+	if d.DiffStat {
+		fetchers = append(fetchers, parseDiffStat)
+	}
 
-	go func() {
-		defer wg.Done()
-		parseAheadBehind(ctx, dir, info)
-	}()
+	var wg sync.WaitGroup
+	for _, fetch := range fetchers {
+		wg.Add(1)
+		go func(fetch fetcher) {
+			defer wg.Done()
+			fetch(ctx, repo, dir, info)
+		}(fetch)
+	}
 
 	wg.Wait()
 
 	// 更新快取
+	updateMemoryCache(key, info)
+	saveFileCache(root, state, info)
+
+	return info
+}
+
+// fetcher 填入 GitStatusInfo 的部分欄位。repo 為 nil 時（reader 無法開啟倉庫）
+// 各 fetcher 自行退回 git CLI。
+type fetcher func(ctx context.Context, repo *git.Repo, dir string, info *GitStatusInfo)
+
+func updateMemoryCache(key string, info *GitStatusInfo) {
 	statusMutex.Lock()
-	statusCache = info
-	statusExpires = time.Now().Add(5 * time.Second)
+	statusCache[key] = statusEntry{info: info, expires: time.Now().Add(statusCacheTTL)}
 	statusMutex.Unlock()
+}
 
-	return info
+// repoState 組出倉庫目前狀態的指紋：HEAD ref 與 commit、index mtime、進行中的操作。
+// 非 git 目錄或無法解析 HEAD 時回傳空字串（不使用檔案快取）。
+func repoState(repo *git.Repo) string {
+	if repo == nil {
+		return ""
+	}
+
+	// commit 後 HEAD 檔案本身不會改變，因此用解析後的 commit 而非 HEAD 內容
+	ref, sha, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	parts := []string{ref, sha}
+
+	if fi, err := os.Stat(filepath.Join(repo.GitDir, "index")); err == nil {
+		parts = append(parts, strconv.FormatInt(fi.ModTime().UnixNano(), 10))
+	}
+
+	// 進行中的操作（例如 rebase 的步驟推進）也納入指紋
+	parts = append(parts, detectOperation(repo.GitDir).String())
+
+	return strings.Join(parts, "|")
+}
+
+// cacheState 檔案快取的狀態指紋：倉庫狀態加上啟用的子欄位，
+// 避免以較少子欄位取得的結果被需要更多子欄位的設定沿用
+func cacheState(repo *git.Repo, d Details) string {
+	state := repoState(repo)
+	if state == "" {
+		return ""
+	}
+	return state + "|" + d.key()
+}
+
+func getCachePath(root string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha1.Sum([]byte(root))
+	name := fmt.Sprintf("gitstatus-%s.json", hex.EncodeToString(sum[:8]))
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", name)
+}
+
+func loadFileCache(root, state string) *GitStatusInfo {
+	if state == "" {
+		return nil
+	}
+	path := getCachePath(root)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var cached cachedStatus
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+
+	if cached.Root != root || cached.State != state || time.Now().Unix() > cached.ExpiresAt {
+		return nil
+	}
+
+	return &cached.Info
 }
 
-func parsePorcelain(ctx context.Context, dir string, info *GitStatusInfo) {
+func saveFileCache(root, state string, info *GitStatusInfo) {
+	if state == "" {
+		return
+	}
+	path := getCachePath(root)
+	if path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	cached := cachedStatus{
+		Root:      root,
+		State:     state,
+		Info:      *info,
+		ExpiresAt: time.Now().Add(statusCacheTTL).Unix(),
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, 0644)
+}
+
+// parsePorcelain 是唯一一定要啟動 git 的 fetcher：working tree 狀態需要比對檔案內容
+func parsePorcelain(ctx context.Context, _ *git.Repo, dir string, info *GitStatusInfo) {
 	cmd := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	parsePorcelainOutput(string(output), info)
+}
+
+// parsePorcelainOutput 解析 `git status --porcelain` 輸出並累加到 info
+func parsePorcelainOutput(output string, info *GitStatusInfo) {
+	// 只去掉結尾換行：XY 的首欄可能是空白（" M file"），TrimSpace 會破壞第一行的欄位對齊
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
 	for _, line := range lines {
 		if len(line) < 2 {
 			continue
@@ -92,6 +262,19 @@ func parsePorcelain(ctx context.Context, dir string, info *GitStatusInfo) {
 		info.IsDirty = true
 		xy := line[:2]
 
+		if isUnmerged(xy) {
+			info.Conflicts++
+			continue
+		}
+		if xy != "??" && xy != "!!" {
+			if xy[0] != ' ' {
+				info.Staged++
+			}
+			if xy[1] != ' ' {
+				info.Unstaged++
+			}
+		}
+
 		switch {
 		case xy[0] == '?' || xy[1] == '?':
 			info.Untracked++
@@ -107,24 +290,54 @@ func parsePorcelain(ctx context.Context, dir string, info *GitStatusInfo) {
 	}
 }
 
-func parseAheadBehind(ctx context.Context, dir string, info *GitStatusInfo) {
-	// 取得 ahead
-	aheadCmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-list", "--count", "@{upstream}..HEAD")
-	if output, err := aheadCmd.Output(); err == nil {
-		if n, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil {
-			info.Ahead = n
-		}
+// isUnmerged 判斷 porcelain XY 是否為 unmerged（衝突）狀態。
+// 見 git-status(1)：DD、AU、UD、UA、DU、AA、UU。
+func isUnmerged(xy string) bool {
+	switch xy {
+	case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+		return true
 	}
+	return false
+}
 
-	// 取得 behind
-	behindCmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-list", "--count", "HEAD..@{upstream}")
-	if output, err := behindCmd.Output(); err == nil {
-		if n, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil {
-			info.Behind = n
+// parseAheadBehind 計算與 upstream 的 ahead/behind。
+// reader 可用時先從檔案解析 upstream：未設定 upstream 或兩端 commit 相同時不需啟動 git；
+// 其餘情況以單一 rev-list --left-right 取得兩個數字（需要走訪 commit graph）。
+func parseAheadBehind(ctx context.Context, repo *git.Repo, dir string, info *GitStatusInfo) {
+	if repo != nil {
+		upstream := repo.Upstream()
+		if upstream == "" {
+			return
+		}
+		_, head, err := repo.Head()
+		if err == nil && head != "" {
+			if up, err := repo.ResolveRef(upstream); err == nil && up == head {
+				return
+			}
 		}
 	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return
+	}
+	if n, err := strconv.Atoi(fields[0]); err == nil {
+		info.Ahead = n
+	}
+	if n, err := strconv.Atoi(fields[1]); err == nil {
+		info.Behind = n
+	}
 }
 
+// StagedCounts 以 staged/unstaged 項目數（●N !N）取代依變更種類的 !modified +added ✘deleted；
+// 由 main 依設定 git_counts 寫入
+var StagedCounts bool
+
 // Format 格式化 Git 狀態為顯示字串
 func Format(info *GitStatusInfo) string {
 	if info == nil {
@@ -133,6 +346,14 @@ func Format(info *GitStatusInfo) string {
 
 	var parts []string
 
+	// 進行中的操作以 git-prompt.sh 風格接在分支名後（main|REBASE 3/7）
+	if op := info.Operation.String(); op != "" {
+		parts = append(parts, "|"+op)
+		if info.IsDirty || info.Ahead > 0 || info.Behind > 0 {
+			parts = append(parts, " ")
+		}
+	}
+
 	if info.IsDirty {
 		parts = append(parts, "*")
 	}
@@ -144,16 +365,28 @@ func Format(info *GitStatusInfo) string {
 		parts = append(parts, fmt.Sprintf("↓%d", info.Behind))
 	}
 
-	// 檔案統計
+	// 檔案統計（= 衝突沿用 starship 符號）。依種類與依 staged/unstaged 的計數互相重疊，只擇一顯示
 	var stats []string
-	if info.Modified > 0 {
-		stats = append(stats, fmt.Sprintf("!%d", info.Modified))
+	if info.Conflicts > 0 {
+		stats = append(stats, fmt.Sprintf("=%d", info.Conflicts))
 	}
-	if info.Added > 0 {
-		stats = append(stats, fmt.Sprintf("+%d", info.Added))
-	}
-	if info.Deleted > 0 {
-		stats = append(stats, fmt.Sprintf("✘%d", info.Deleted))
+	if StagedCounts {
+		if info.Staged > 0 {
+			stats = append(stats, fmt.Sprintf("●%d", info.Staged))
+		}
+		if info.Unstaged > 0 {
+			stats = append(stats, fmt.Sprintf("!%d", info.Unstaged))
+		}
+	} else {
+		if info.Modified > 0 {
+			stats = append(stats, fmt.Sprintf("!%d", info.Modified))
+		}
+		if info.Added > 0 {
+			stats = append(stats, fmt.Sprintf("+%d", info.Added))
+		}
+		if info.Deleted > 0 {
+			stats = append(stats, fmt.Sprintf("✘%d", info.Deleted))
+		}
 	}
 	if info.Untracked > 0 {
 		stats = append(stats, fmt.Sprintf("?%d", info.Untracked))
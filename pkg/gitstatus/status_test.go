@@ -1,8 +1,14 @@
 package gitstatus
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/git"
 )
 
 func TestFormatEmpty(t *testing.T) {
@@ -83,3 +89,308 @@ func TestFormatFullStatus(t *testing.T) {
 		return
 	}
 }
+
+func TestFormatStagedCounts(t *testing.T) {
+	StagedCounts = true
+	defer func() { StagedCounts = false }()
+
+	// 依 staged/unstaged 計數時，同一個檔案不會同時算進 ● 與 !
+	tests := []struct {
+		porcelain string
+		want      string
+	}{
+		{"M  staged.go\n", "*●1"},
+		{" M unstaged.go\n", "*!1"},
+		{"MM both.go\n", "*●1!1"},
+		{"A  added.go\n D deleted.go\n", "*●1!1"},
+	}
+	for _, tt := range tests {
+		info := &GitStatusInfo{}
+		parsePorcelainOutput(tt.porcelain, info)
+		if got := Format(info); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.porcelain, got, tt.want)
+		}
+	}
+}
+
+func TestGetCacheKeyedByRepo(t *testing.T) {
+	ClearCache()
+	t.Setenv("HOME", t.TempDir())
+
+	clean := initRepo(t)
+	dirty := initRepo(t)
+	if err := os.WriteFile(filepath.Join(dirty, "new.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 連續查詢兩個倉庫，第二個不應拿到第一個的快取
+	if info := Get(clean, Details{}); info.IsDirty {
+		t.Fatalf("expected clean repo, got %+v", info)
+	}
+	if info := Get(dirty, Details{}); !info.IsDirty || info.Untracked != 1 {
+		t.Fatalf("expected dirty repo with 1 untracked, got %+v", info)
+	}
+}
+
+func TestGetFileCache(t *testing.T) {
+	ClearCache()
+	t.Setenv("HOME", t.TempDir())
+
+	repo := initRepo(t)
+	first := Get(repo, Details{})
+	if first.IsDirty {
+		t.Fatalf("expected clean repo, got %+v", first)
+	}
+
+	// 模擬新的 statusline 行程：清記憶體快取後應從檔案快取取得
+	ClearCache()
+	root := git.CacheKey(repo)
+	if cached := loadFileCache(root, cacheState(git.Open(repo), Details{})); cached == nil {
+		t.Fatal("expected file cache hit for unchanged repo")
+	}
+
+	// stage 檔案會改變 index mtime，檔案快取應失效
+	if err := os.WriteFile(filepath.Join(repo, "staged.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "staged.txt")
+	if cached := loadFileCache(root, cacheState(git.Open(repo), Details{})); cached != nil {
+		t.Fatalf("expected file cache miss after staging, got %+v", cached)
+	}
+	if info := Get(repo, Details{}); info.Added != 1 {
+		t.Fatalf("expected 1 added file after staging, got %+v", info)
+	}
+}
+
+// initRepo 建立含一個 commit 的臨時 git 倉庫
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "commit", "--allow-empty", "-m", "Initial commit")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestParsePorcelainOutput(t *testing.T) {
+	output := " M unstaged.go\nM  staged.go\nMM both.go\nA  added.go\nUU conflict.go\nAA both-added.go\nDU deleted-by-us.go\n?? new.txt\n"
+	info := &GitStatusInfo{}
+	parsePorcelainOutput(output, info)
+
+	if info.Conflicts != 3 {
+		t.Errorf("expected 3 conflicts, got %d", info.Conflicts)
+	}
+	// staged: "M ", "MM", "A "；unstaged: " M", "MM"
+	if info.Staged != 3 {
+		t.Errorf("expected 3 staged, got %d", info.Staged)
+	}
+	if info.Unstaged != 2 {
+		t.Errorf("expected 2 unstaged, got %d", info.Unstaged)
+	}
+	if info.Modified != 3 {
+		t.Errorf("expected 3 modified, got %d", info.Modified)
+	}
+	if info.Added != 1 {
+		t.Errorf("expected 1 added, got %d", info.Added)
+	}
+	if info.Untracked != 1 {
+		t.Errorf("expected 1 untracked, got %d", info.Untracked)
+	}
+}
+
+func TestDetectOperation(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"none", nil, ""},
+		{"rebase-merge", map[string]string{"rebase-merge/msgnum": "3\n", "rebase-merge/end": "7\n"}, "REBASE 3/7"},
+		{"rebase-apply", map[string]string{"rebase-apply/rebasing": "", "rebase-apply/next": "2", "rebase-apply/last": "5"}, "REBASE 2/5"},
+		{"am", map[string]string{"rebase-apply/applying": "", "rebase-apply/next": "1", "rebase-apply/last": "4"}, "AM 1/4"},
+		{"merge", map[string]string{"MERGE_HEAD": "abc\n"}, "MERGING"},
+		{"cherry-pick", map[string]string{"CHERRY_PICK_HEAD": "abc\n"}, "CHERRY-PICKING"},
+		{"revert", map[string]string{"REVERT_HEAD": "abc\n"}, "REVERTING"},
+		{"bisect", map[string]string{"BISECT_LOG": "# bad\n"}, "BISECTING"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(gitDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := detectOperation(gitDir).String(); got != tc.want {
+				t.Errorf("detectOperation() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatOperationAndConflicts(t *testing.T) {
+	info := &GitStatusInfo{
+		IsDirty:   true,
+		Conflicts: 2,
+		Modified:  1,
+		Operation: Operation{Name: OpRebase, Step: 3, Total: 7},
+	}
+	if got := Format(info); got != "|REBASE 3/7 *=2!1" {
+		t.Fatalf("expected operation, conflicts and modified count, got %q", got)
+	}
+
+	// 僅有操作、工作區乾淨時不加尾端空白
+	if got := Format(&GitStatusInfo{Operation: Operation{Name: OpBisecting}}); got != "|BISECTING" {
+		t.Fatalf("expected %q, got %q", "|BISECTING", got)
+	}
+}
+
+func TestGetDetectsMergeConflict(t *testing.T) {
+	ClearCache()
+	t.Setenv("HOME", t.TempDir())
+
+	repo := initRepo(t)
+	path := filepath.Join(repo, "file.txt")
+	writeAndCommit := func(content, msg string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, repo, "add", "file.txt")
+		runGit(t, repo, "commit", "-m", msg)
+	}
+	writeAndCommit("base\n", "base")
+	runGit(t, repo, "checkout", "-b", "other")
+	writeAndCommit("other\n", "other")
+	runGit(t, repo, "checkout", "-")
+	writeAndCommit("main\n", "main")
+
+	// 預期衝突，忽略 merge 的非零結束碼
+	_ = exec.Command("git", "-C", repo, "merge", "other").Run()
+
+	info := Get(repo, Details{})
+	if info.Operation.Name != OpMerging {
+		t.Errorf("expected MERGING, got %q", info.Operation.Name)
+	}
+	if info.Conflicts != 1 {
+		t.Errorf("expected 1 conflict, got %d", info.Conflicts)
+	}
+}
+
+func TestParseShortstat(t *testing.T) {
+	tests := []struct {
+		in       string
+		ins, del int
+	}{
+		{" 3 files changed, 12 insertions(+), 3 deletions(-)\n", 12, 3},
+		{" 1 file changed, 1 insertion(+)\n", 1, 0},
+		{" 1 file changed, 2 deletions(-)\n", 0, 2},
+		{"", 0, 0},
+	}
+	for _, tc := range tests {
+		ins, del := parseShortstat(tc.in)
+		if ins != tc.ins || del != tc.del {
+			t.Errorf("parseShortstat(%q) = (%d, %d), want (%d, %d)", tc.in, ins, del, tc.ins, tc.del)
+		}
+	}
+}
+
+func TestFormatDetails(t *testing.T) {
+	info := &GitStatusInfo{
+		Stashes:           2,
+		Insertions:        12,
+		Deletions:         3,
+		LastCommitTime:    time.Now().Add(-3 * time.Hour),
+		LastCommitSubject: "fix: handle detached HEAD in the git segment",
+	}
+
+	all := FormatDetails(info, Details{Stash: true, DiffStat: true, LastCommit: true})
+	for _, want := range []string{"⚑2", "Δ+12/-3", "3h fix: handle detached"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("expected %q in %q", want, all)
+		}
+	}
+	if !strings.HasSuffix(all, "…") {
+		t.Errorf("expected long subject to be truncated, got %q", all)
+	}
+
+	if got := FormatDetails(info, Details{}); got != "" {
+		t.Errorf("expected empty string with no details enabled, got %q", got)
+	}
+	if got := FormatDetails(&GitStatusInfo{}, Details{Stash: true, DiffStat: true, LastCommit: true}); got != "" {
+		t.Errorf("expected empty string for zero info, got %q", got)
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	ClearCache()
+	t.Setenv("HOME", t.TempDir())
+
+	repo := initRepo(t)
+	path := filepath.Join(repo, "file.txt")
+	if err := os.WriteFile(path, []byte("a\nb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "file.txt")
+	runGit(t, repo, "commit", "-m", "add file")
+
+	// 一筆 stash + 未 commit 的變更
+	if err := os.WriteFile(path, []byte("stashed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "stash")
+	if err := os.WriteFile(path, []byte("a\nc\nd\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未啟用的子欄位不取得
+	if info := Get(repo, Details{}); info.Stashes != 0 || info.LastCommitSubject != "" || info.Insertions != 0 {
+		t.Errorf("expected no details when disabled, got %+v", info)
+	}
+
+	info := Get(repo, Details{Stash: true, DiffStat: true, LastCommit: true})
+	if info.Stashes != 1 {
+		t.Errorf("expected 1 stash, got %d", info.Stashes)
+	}
+	if info.LastCommitSubject != "add file" {
+		t.Errorf("expected last commit subject %q, got %q", "add file", info.LastCommitSubject)
+	}
+	if info.Insertions != 2 || info.Deletions != 1 {
+		t.Errorf("expected +2/-1, got +%d/-%d", info.Insertions, info.Deletions)
+	}
+}
+
+func TestGetAheadBehindLocalUpstream(t *testing.T) {
+	ClearCache()
+	t.Setenv("HOME", t.TempDir())
+
+	repo := initRepo(t)
+	runGit(t, repo, "branch", "base")
+	runGit(t, repo, "checkout", "-b", "feature")
+	runGit(t, repo, "branch", "--set-upstream-to", "base")
+
+	// 與 upstream 相同：reader 直接判定 0/0
+	if info := Get(repo, Details{}); info.Ahead != 0 || info.Behind != 0 {
+		t.Fatalf("expected in sync with upstream, got ↑%d ↓%d", info.Ahead, info.Behind)
+	}
+
+	runGit(t, repo, "commit", "--allow-empty", "-m", "ahead 1")
+	runGit(t, repo, "commit", "--allow-empty", "-m", "ahead 2")
+	ClearCache()
+	if info := Get(repo, Details{}); info.Ahead != 2 || info.Behind != 0 {
+		t.Fatalf("expected ↑2 ↓0, got ↑%d ↓%d", info.Ahead, info.Behind)
+	}
+}
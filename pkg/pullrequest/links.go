@@ -0,0 +1,86 @@
+package pullrequest
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/git"
+)
+
+// RepoURL 回傳 dir 所屬倉庫的網頁位址：優先使用目前分支設定的 remote，其次為 origin。
+// 非 git 目錄或 remote 無法轉為網址時回傳空字串。
+func RepoURL(dir string) string {
+	repo := git.Open(dir)
+	if repo == nil {
+		return ""
+	}
+	branch, _ := repo.Branch()
+	return branchWebURL(repo, branch)
+}
+
+// BranchURL 回傳分支的網頁連結。tmpl 非空時展開 {repo_url}、{branch}；
+// 否則為 <倉庫網頁>/tree/<branch>。無法產生時回傳空字串。
+func BranchURL(dir, branch, tmpl string) string {
+	if branch == "" {
+		return ""
+	}
+	if tmpl != "" {
+		return expandURL(tmpl, RepoURL(dir), branch, 0)
+	}
+	if base := RepoURL(dir); base != "" {
+		return base + "/tree/" + branch
+	}
+	return ""
+}
+
+// PRURL 回傳 PR 的網頁連結。tmpl 非空時展開 {repo_url}、{number}、{branch}
+// （用於 GitHub 以外的 code review 系統）；否則沿用偵測到的 info.URL。
+func PRURL(info *PRInfo, dir, tmpl string) string {
+	if info == nil {
+		return ""
+	}
+	if tmpl == "" {
+		return info.URL
+	}
+	branch := ""
+	if repo := git.Open(dir); repo != nil {
+		branch, _ = repo.Branch()
+	}
+	return expandURL(tmpl, RepoURL(dir), branch, info.Number)
+}
+
+// branchWebURL 取得分支 upstream remote（未設定時為 origin）的網頁位址。
+// branch.<name>.remote 可能是 remote 名稱或完整 URL。
+func branchWebURL(repo *git.Repo, branch string) string {
+	remote := ""
+	if branch != "" {
+		remote = repo.BranchConfig(branch, "remote")
+	}
+	if remote == "" {
+		remote = "origin"
+	}
+	if !strings.Contains(remote, "/") && !strings.Contains(remote, ":") {
+		remote = repo.RemoteURL(remote)
+	}
+	return WebURL(remote)
+}
+
+// expandURL 展開連結樣板；樣板用到的值缺少時回傳空字串，避免產生殘缺的連結
+func expandURL(tmpl, repoURL, branch string, number int) string {
+	vars := map[string]string{"{repo_url}": repoURL, "{branch}": branch}
+	if number > 0 {
+		vars["{number}"] = strconv.Itoa(number)
+	} else {
+		vars["{number}"] = ""
+	}
+	for key, val := range vars {
+		if !strings.Contains(tmpl, key) {
+			continue
+		}
+		if val == "" {
+			return ""
+		}
+		tmpl = strings.ReplaceAll(tmpl, key, val)
+	}
+	return tmpl
+}
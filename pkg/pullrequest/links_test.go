@@ -0,0 +1,45 @@
+package pullrequest
+
+import "testing"
+
+func TestBranchURL(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/feature/login")
+
+	if got := BranchURL(dir, "feature/login", ""); got != "" {
+		t.Errorf("expected empty URL without remote, got %q", got)
+	}
+
+	runGit(t, dir, "config", "remote.origin.url", "git@github.com:owner/repo.git")
+	if got, want := BranchURL(dir, "feature/login", ""), "https://github.com/owner/repo/tree/feature/login"; got != want {
+		t.Errorf("BranchURL = %q, want %q", got, want)
+	}
+	if got, want := BranchURL(dir, "feature/login", "{repo_url}/src/{branch}"), "https://github.com/owner/repo/src/feature/login"; got != want {
+		t.Errorf("BranchURL with template = %q, want %q", got, want)
+	}
+	if got := BranchURL(dir, "", ""); got != "" {
+		t.Errorf("expected empty URL without branch, got %q", got)
+	}
+}
+
+func TestPRURL(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/fix")
+	runGit(t, dir, "config", "remote.origin.url", "https://git.example.com/team/app.git")
+
+	info := &PRInfo{Number: 7, URL: "https://github.com/o/r/pull/7"}
+	if got := PRURL(info, dir, ""); got != info.URL {
+		t.Errorf("expected detected URL without template, got %q", got)
+	}
+	if got, want := PRURL(info, dir, "https://review.example.com/{number}?branch={branch}"), "https://review.example.com/7?branch=fix"; got != want {
+		t.Errorf("PRURL = %q, want %q", got, want)
+	}
+	if got, want := PRURL(info, dir, "{repo_url}/pulls/{number}"), "https://git.example.com/team/app/pulls/7"; got != want {
+		t.Errorf("PRURL = %q, want %q", got, want)
+	}
+	if got := PRURL(nil, dir, "{repo_url}"); got != "" {
+		t.Errorf("expected empty URL for nil info, got %q", got)
+	}
+}
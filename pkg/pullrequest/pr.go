@@ -0,0 +1,165 @@
+package pullrequest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/git"
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// PRInfo 代表目前工作關聯的 pull request
+type PRInfo struct {
+	Number int
+	URL    string // 網頁連結；無法推導時為空字串
+	Source string // "transcript" 或 "branch"
+}
+
+// 來源標籤
+const (
+	SourceTranscript = "transcript"
+	SourceBranch     = "branch"
+)
+
+// prURLPattern 從 PR 網址取出編號（GitHub /pull/N、GitLab /merge_requests/N、Bitbucket /pull-requests/N）
+var prURLPattern = regexp.MustCompile(`/(?:pull|merge_requests|pull-requests)/(\d+)`)
+
+// mergeRefPattern 為 gh / glab checkout PR 時寫入 branch.<name>.merge 的 ref
+var mergeRefPattern = regexp.MustCompile(`^refs/(pull|merge-requests)/(\d+)/head$`)
+
+// Find 依序從 transcript 與分支 upstream 設定找出 PR，都找不到時回傳 nil。
+// 只使用本地資料，不發任何網路請求。
+func Find(lines []transcript.Line, dir string) *PRInfo {
+	if info := FromTranscript(lines); info != nil {
+		return info
+	}
+	return FromBranch(dir)
+}
+
+// FromTranscript 從 transcript 的 pr-link metadata 取出最新的 PR
+func FromTranscript(lines []transcript.Line) *PRInfo {
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		if l.Parsed == nil {
+			continue
+		}
+		if t, _ := l.Parsed["type"].(string); t != "pr-link" {
+			continue
+		}
+
+		info := &PRInfo{Source: SourceTranscript}
+		for _, key := range []string{"prUrl", "url"} {
+			if u, ok := l.Parsed[key].(string); ok && u != "" {
+				info.URL = u
+				break
+			}
+		}
+		if n, ok := l.Parsed["prNumber"].(float64); ok {
+			info.Number = int(n)
+		}
+		if info.Number == 0 && info.URL != "" {
+			info.Number = numberFromURL(info.URL)
+		}
+		if info.Number > 0 {
+			return info
+		}
+	}
+	return nil
+}
+
+// FromBranch 從目前分支的 upstream 設定推導 PR。
+// gh / glab checkout PR 時會把 branch.<name>.merge 設為 refs/pull/N/head
+// （GitLab 為 refs/merge-requests/N/head），remote 則為 remote 名稱或完整 URL。
+func FromBranch(dir string) *PRInfo {
+	repo := git.Open(dir)
+	if repo == nil {
+		return nil
+	}
+	branch, err := repo.Branch()
+	if err != nil || branch == "" {
+		return nil
+	}
+
+	m := mergeRefPattern.FindStringSubmatch(repo.BranchConfig(branch, "merge"))
+	if m == nil {
+		return nil
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return nil
+	}
+
+	info := &PRInfo{Number: n, Source: SourceBranch}
+
+	if base := branchWebURL(repo, branch); base != "" {
+		if m[1] == "merge-requests" {
+			info.URL = fmt.Sprintf("%s/-/merge_requests/%d", base, n)
+		} else {
+			info.URL = fmt.Sprintf("%s/pull/%d", base, n)
+		}
+	}
+	return info
+}
+
+// numberFromURL 從 PR 網址取出編號，失敗時回傳 0
+func numberFromURL(u string) int {
+	m := prURLPattern.FindStringSubmatch(u)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// WebURL 將 git remote URL 轉為倉庫網頁位址（https://host/owner/repo）。
+// 支援 scp 形式（git@host:owner/repo.git）、ssh://、git:// 與 http(s)://；無法解析時回傳空字串。
+func WebURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	var host, path string
+	if scheme, rest, ok := strings.Cut(remote, "://"); ok {
+		switch scheme {
+		case "https", "http", "ssh", "git":
+		default:
+			return ""
+		}
+		hostPart, p, _ := strings.Cut(rest, "/")
+		if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+			hostPart = hostPart[at+1:]
+		}
+		// ssh 的 port 不屬於網頁位址；http(s) 保留
+		if scheme == "ssh" || scheme == "git" {
+			hostPart, _, _ = strings.Cut(hostPart, ":")
+		}
+		host, path = hostPart, p
+	} else {
+		// scp 形式：[user@]host:path
+		hostPart, p, ok := strings.Cut(remote, ":")
+		if !ok || strings.Contains(hostPart, "/") {
+			return ""
+		}
+		if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+			hostPart = hostPart[at+1:]
+		}
+		host, path = hostPart, p
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return ""
+	}
+	return "https://" + host + "/" + path
+}
+
+// Format 格式化 PR 顯示文字（不含超連結與顏色）
+func Format(info *PRInfo) string {
+	if info == nil || info.Number <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("PR #%d", info.Number)
+}
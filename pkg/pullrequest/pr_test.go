@@ -0,0 +1,118 @@
+package pullrequest
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestFromTranscript(t *testing.T) {
+	lines := []transcript.Line{
+		{Parsed: map[string]interface{}{"type": "pr-link", "prNumber": float64(12), "prUrl": "https://github.com/o/r/pull/12"}},
+		{Parsed: map[string]interface{}{"type": "assistant"}},
+		{Parsed: map[string]interface{}{"type": "pr-link", "prNumber": float64(213), "prUrl": "https://github.com/o/r/pull/213"}},
+		{Parsed: map[string]interface{}{"type": "user"}},
+	}
+
+	info := FromTranscript(lines)
+	if info == nil {
+		t.Fatal("expected PR info")
+	}
+	if info.Number != 213 || info.URL != "https://github.com/o/r/pull/213" || info.Source != SourceTranscript {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := Format(info); got != "PR #213" {
+		t.Errorf("Format = %q, want %q", got, "PR #213")
+	}
+}
+
+func TestFromTranscriptNumberFromURL(t *testing.T) {
+	lines := []transcript.Line{
+		{Parsed: map[string]interface{}{"type": "pr-link", "url": "https://gitlab.com/g/p/-/merge_requests/7"}},
+	}
+	info := FromTranscript(lines)
+	if info == nil || info.Number != 7 {
+		t.Errorf("expected MR 7 from URL, got %+v", info)
+	}
+
+	if info := FromTranscript([]transcript.Line{{Parsed: map[string]interface{}{"type": "assistant"}}}); info != nil {
+		t.Errorf("expected nil without pr-link, got %+v", info)
+	}
+}
+
+func TestFromBranch(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/fix-login")
+	runGit(t, dir, "config", "remote.origin.url", "git@github.com:owner/repo.git")
+
+	if info := FromBranch(dir); info != nil {
+		t.Fatalf("expected nil without PR merge ref, got %+v", info)
+	}
+
+	runGit(t, dir, "config", "branch.fix-login.remote", "origin")
+	runGit(t, dir, "config", "branch.fix-login.merge", "refs/pull/42/head")
+	info := FromBranch(dir)
+	if info == nil {
+		t.Fatal("expected PR info from branch config")
+	}
+	if info.Number != 42 || info.URL != "https://github.com/owner/repo/pull/42" || info.Source != SourceBranch {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	// gh 對 fork 的 PR 會把 remote 直接寫成 URL
+	runGit(t, dir, "config", "branch.fix-login.remote", "https://gitlab.example.com/team/app.git")
+	runGit(t, dir, "config", "branch.fix-login.merge", "refs/merge-requests/9/head")
+	info = FromBranch(dir)
+	if info == nil || info.URL != "https://gitlab.example.com/team/app/-/merge_requests/9" {
+		t.Errorf("unexpected GitLab info: %+v", info)
+	}
+}
+
+func TestFindPrefersTranscript(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/pr")
+	runGit(t, dir, "config", "branch.pr.remote", "origin")
+	runGit(t, dir, "config", "branch.pr.merge", "refs/pull/1/head")
+
+	lines := []transcript.Line{
+		{Parsed: map[string]interface{}{"type": "pr-link", "prNumber": float64(5)}},
+	}
+	if info := Find(lines, dir); info == nil || info.Number != 5 {
+		t.Errorf("expected transcript PR 5, got %+v", info)
+	}
+	if info := Find(nil, dir); info == nil || info.Number != 1 || info.URL != "" {
+		t.Errorf("expected branch PR 1 without URL, got %+v", info)
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:owner/repo.git", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo.git", "https://github.com/owner/repo"},
+		{"https://user@github.com/owner/repo", "https://github.com/owner/repo"},
+		{"ssh://git@github.com:22/owner/repo.git", "https://github.com/owner/repo"},
+		{"https://git.example.com:8443/team/app.git", "https://git.example.com:8443/team/app"},
+		{"/local/path/repo.git", ""},
+		{"file:///tmp/repo.git", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := WebURL(tt.remote); got != tt.want {
+			t.Errorf("WebURL(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package speed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// LatencyInfo API 延遲統計
+type LatencyInfo struct {
+	Responses   int   `json:"responses"`     // session 內的 API 回應數（不重複的 assistant message.id，含子代理）
+	AvgAPIMs    int64 `json:"avg_api_ms"`    // 每個回應平均的 API 時間；無法計算時為 0
+	APISharePct int   `json:"api_share_pct"` // API 時間佔 session 經過時間的百分比；無法計算時為 0
+	TTFTMs      int64 `json:"ttft_ms"`       // 最近回合的首個輸出估計（請求到第一行 assistant 訊息）；無資料時為 0
+}
+
+// Latency 由 Claude Code 提供的累計 API 時間與經過時間，加上 transcript 回合資料計算延遲統計。
+// TTFT 以 assistant 第一行寫入的時間估計（第一個 content block 完成時才寫入），因此略高於實際值。
+func Latency(apiMs, wallMs int64, responses int, turns []Turn, window int) *LatencyInfo {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	info := &LatencyInfo{Responses: responses}
+	if apiMs > 0 && responses > 0 {
+		info.AvgAPIMs = apiMs / int64(responses)
+	}
+	if apiMs > 0 && wallMs > 0 {
+		info.APISharePct = int(apiMs * 100 / wallMs)
+		if info.APISharePct > 100 {
+			info.APISharePct = 100
+		}
+	}
+
+	var total int64
+	used := 0
+	for i := len(turns) - 1; i >= 0 && used < window; i-- {
+		t := turns[i]
+		if t.StartMs <= 0 || t.FirstMs < t.StartMs {
+			continue
+		}
+		total += t.FirstMs - t.StartMs
+		used++
+	}
+	if used > 0 {
+		info.TTFTMs = total / int64(used)
+	}
+
+	if info.AvgAPIMs == 0 && info.APISharePct == 0 && info.TTFTMs == 0 {
+		return nil
+	}
+	return info
+}
+
+// FormatLatency 格式化延遲統計（如 "api 4.2s/turn 63% · ttft ~1.8s"）
+func FormatLatency(info *LatencyInfo) string {
+	if info == nil {
+		return ""
+	}
+	var parts []string
+	if info.AvgAPIMs > 0 {
+		api := fmt.Sprintf("api %s/turn", formatMs(info.AvgAPIMs))
+		if info.APISharePct > 0 {
+			api += fmt.Sprintf(" %d%%", info.APISharePct)
+		}
+		parts = append(parts, api)
+	} else if info.APISharePct > 0 {
+		parts = append(parts, fmt.Sprintf("api %d%%", info.APISharePct))
+	}
+	if info.TTFTMs > 0 {
+		parts = append(parts, "ttft ~"+formatMs(info.TTFTMs))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatMs 格式化毫秒數：10 秒以下保留一位小數
+func formatMs(ms int64) string {
+	switch {
+	case ms < 10000:
+		return fmt.Sprintf("%.1fs", float64(ms)/1000)
+	case ms < 60000:
+		return fmt.Sprintf("%ds", ms/1000)
+	default:
+		return fmt.Sprintf("%dm%02ds", ms/60000, (ms%60000)/1000)
+	}
+}
+
+// SessionResponses 增量掃描整份 transcript（與 cache 共用 transcript.ScanSession），
+// 回傳 session 內的 API 回應數；無法讀取時回傳 0
+func SessionResponses(transcriptPath, sessionID string) int {
+	scan := transcript.ScanSession(transcriptPath, sessionID)
+	if scan == nil {
+		return 0
+	}
+	return scan.Responses
+}
@@ -0,0 +1,67 @@
+package speed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+func TestLatency(t *testing.T) {
+	lines := []transcript.Line{
+		userLine("2026-01-01T00:00:00Z"),
+		assistantLine("2026-01-01T00:00:02Z", "msg_1", "claude-sonnet-4-5", 10),
+		assistantLine("2026-01-01T00:00:09Z", "msg_1", "claude-sonnet-4-5", 300),
+		userLine("2026-01-01T00:00:10Z"),
+		assistantLine("2026-01-01T00:00:11Z", "msg_2", "claude-sonnet-4-5", 50),
+	}
+
+	info := Latency(12000, 40000, 3, ExtractTurns(lines), 5)
+	if info == nil {
+		t.Fatal("expected LatencyInfo")
+	}
+	if info.AvgAPIMs != 4000 || info.APISharePct != 30 || info.TTFTMs != 1500 {
+		t.Errorf("unexpected latency: %+v", info)
+	}
+	if got := FormatLatency(info); got != "api 4.0s/turn 30% · ttft ~1.5s" {
+		t.Errorf("unexpected format: %q", got)
+	}
+
+	if Latency(0, 0, 0, nil, 5) != nil {
+		t.Error("expected nil without any data")
+	}
+	if got := FormatLatency(&LatencyInfo{APISharePct: 80}); got != "api 80%" {
+		t.Errorf("unexpected share-only format: %q", got)
+	}
+}
+
+func TestSessionResponses(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	content := `{"message":{"id":"m1","role":"assistant","model":"claude-sonnet-4-5"}}
+{"message":{"id":"m1","role":"assistant","model":"claude-sonnet-4-5"}}
+{"isSidechain":true,"message":{"id":"m2","role":"assistant","model":"claude-haiku-4-5"}}
+{"message":{"id":"m3","role":"assistant","model":"<synthetic>"}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := SessionResponses(path, "s1"); got != 2 {
+		t.Fatalf("expected 2 responses, got %d", got)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"message":{"id":"m1","role":"assistant","model":"claude-sonnet-4-5"}}` + "\n" +
+		`{"message":{"id":"m4","role":"assistant","model":"claude-sonnet-4-5"}}` + "\n")
+	f.Close()
+
+	// 增量掃描：m1 已計算過，只新增 m4
+	if got := SessionResponses(path, "s1"); got != 3 {
+		t.Errorf("expected 3 responses, got %d", got)
+	}
+}
@@ -5,115 +5,246 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
 // SpeedInfo 代表輸出速度資訊
 type SpeedInfo struct {
-	TokensPerSec int
+	TokensPerSec int    // 最近 N 個回合的平均輸出速度
+	Model        string // 最近一個回合的模型
+	ModelAvg     int    // 該模型跨 session 的平均輸出速度；沒有歷史資料時為 0
 }
 
-// Measurement 快取的測量值
-type Measurement struct {
-	OutputTokens int   `json:"output_tokens"`
-	TimestampMs  int64 `json:"timestamp_ms"`
+// DefaultWindow 滾動平均預設採用的回合數
+const DefaultWindow = 5
+
+// minTurnMs 回合耗時低於此值時不列入計算（時間戳精度不足，速度會失真）
+const minTurnMs = 500
+
+// Turn 單一 assistant 回合的輸出量與耗時
+type Turn struct {
+	MessageID    string
+	Model        string
+	OutputTokens int
+	StartMs      int64 // 觸發回合的 user 行（使用者訊息或 tool_result）時間
+	FirstMs      int64 // assistant 訊息第一行的時間
+	EndMs        int64 // assistant 訊息最後一行的時間
+	Complete     bool  // 之後已出現下一個 user 行，輸出量不會再變
 }
 
-// Calculate 計算輸出速度（tokens/sec）
-func Calculate(lines []transcript.Line, sessionID string) *SpeedInfo {
-	// 從 transcript 中找出最新的 output_tokens
-	currentTokens := extractOutputTokens(lines)
-	if currentTokens <= 0 {
-		return nil
-	}
+// DurationMs 回合耗時（毫秒）
+func (t Turn) DurationMs() int64 {
+	return t.EndMs - t.StartMs
+}
+
+// measurable 回合是否有足夠資料計算速度
+func (t Turn) measurable() bool {
+	return t.OutputTokens > 0 && t.StartMs > 0 && t.DurationMs() >= minTurnMs
+}
+
+// ExtractTurns 從 transcript 行找出主對話的 assistant 回合。
+// 同一則訊息會拆成多行（每個 content block 一行），以 message.id 合併，取最後一行的時間與 output_tokens。
+func ExtractTurns(lines []transcript.Line) []Turn {
+	var turns []Turn
+	index := make(map[string]int) // message.id -> turns 索引
+	var requestMs int64
+
+	for _, l := range lines {
+		if l.Parsed == nil {
+			continue
+		}
+		// 子代理的 sidechain 行與主對話交錯，會打亂請求時間
+		if side, _ := l.Parsed["isSidechain"].(bool); side {
+			continue
+		}
+		msg, ok := l.Parsed["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ts := l.UnixMilli()
+		role, _ := msg["role"].(string)
 
-	now := time.Now().UnixMilli()
+		switch role {
+		case "user":
+			requestMs = ts
+			for i := range turns {
+				turns[i].Complete = true
+			}
+		case "assistant":
+			id, _ := msg["id"].(string)
+			model, _ := msg["model"].(string)
+			if id == "" || model == "<synthetic>" {
+				continue
+			}
+			output := 0
+			if usage, ok := msg["usage"].(map[string]interface{}); ok {
+				if v, ok := usage["output_tokens"].(float64); ok {
+					output = int(v)
+				}
+			}
 
-	// 讀取上次測量值
-	prev := loadMeasurement(sessionID)
+			i, ok := index[id]
+			if !ok {
+				index[id] = len(turns)
+				turns = append(turns, Turn{MessageID: id, Model: model, StartMs: requestMs, FirstMs: ts, EndMs: ts, OutputTokens: output})
+				continue
+			}
+			if ts > turns[i].EndMs {
+				turns[i].EndMs = ts
+			}
+			if output > turns[i].OutputTokens {
+				turns[i].OutputTokens = output
+			}
+		}
+	}
+	return turns
+}
 
-	// 儲存當前測量值
-	saveMeasurement(sessionID, &Measurement{
-		OutputTokens: currentTokens,
-		TimestampMs:  now,
-	})
+// Calculate 以最近 window 個可計算的回合計算輸出速度（總 tokens / 總耗時），
+// 並將 sessionID 已完成的回合累加進跨 session 的模型平均
+func Calculate(lines []transcript.Line, sessionID string, window int) *SpeedInfo {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	turns := ExtractTurns(lines)
 
-	if prev == nil || prev.OutputTokens <= 0 {
-		return nil
+	var tokens, ms int64
+	var model string
+	used := 0
+	for i := len(turns) - 1; i >= 0 && used < window; i-- {
+		t := turns[i]
+		if !t.measurable() {
+			continue
+		}
+		if model == "" {
+			model = t.Model
+		}
+		tokens += int64(t.OutputTokens)
+		ms += t.DurationMs()
+		used++
 	}
 
-	// 計算 delta
-	tokenDelta := currentTokens - prev.OutputTokens
-	timeDeltaMs := now - prev.TimestampMs
+	averages := loadModelAverages()
+	if averages.add(sessionID, turns) {
+		saveModelAverages(averages)
+	}
 
-	// 只在 2 秒內的測量才計算速度
-	if timeDeltaMs <= 0 || timeDeltaMs > 2000 || tokenDelta <= 0 {
+	if ms <= 0 {
 		return nil
 	}
-
-	tokPerSec := int(float64(tokenDelta) * 1000.0 / float64(timeDeltaMs))
+	tokPerSec := int(float64(tokens) * 1000.0 / float64(ms))
 	if tokPerSec <= 0 {
 		return nil
 	}
+	return &SpeedInfo{TokensPerSec: tokPerSec, Model: model, ModelAvg: averages.get(model)}
+}
 
-	return &SpeedInfo{TokensPerSec: tokPerSec}
+// ModelStat 單一模型累計的輸出量與耗時
+type ModelStat struct {
+	OutputTokens int64 `json:"output_tokens"`
+	DurationMs   int64 `json:"duration_ms"`
+	Turns        int   `json:"turns"`
 }
 
-func extractOutputTokens(lines []transcript.Line) int {
-	for i := len(lines) - 1; i >= 0; i-- {
-		l := lines[i]
-		if l.Parsed == nil {
-			continue
-		}
+// maxModelTurns 累計回合數超過此值時將總量減半，讓平均反映較近期的速度
+const maxModelTurns = 500
 
-		msg, ok := l.Parsed["message"].(map[string]interface{})
-		if !ok {
+// maxSessions 保留累計進度的 session 數，超過時移除最久沒有新回合的 session
+const maxSessions = 100
+
+// modelAverages 各模型跨 session 的輸出速度統計。
+// 累計進度依 session 分開記錄：同時進行的 session 各自推進，不會互相略過回合。
+type modelAverages struct {
+	Models   map[string]*ModelStat `json:"models"`   // key 為完整模型 ID
+	Sessions map[string]int64      `json:"sessions"` // session ID -> 已累計的最後一個回合結束時間
+}
+
+func newModelAverages() *modelAverages {
+	return &modelAverages{Models: make(map[string]*ModelStat), Sessions: make(map[string]int64)}
+}
+
+// add 累加該 session 尚未計入的已完成回合，回傳是否有變動
+func (m *modelAverages) add(sessionID string, turns []Turn) bool {
+	changed := false
+	for _, t := range turns {
+		if !t.Complete || !t.measurable() || t.EndMs <= m.Sessions[sessionID] {
 			continue
 		}
-
-		usage, ok := msg["usage"].(map[string]interface{})
+		st, ok := m.Models[t.Model]
 		if !ok {
-			continue
+			st = &ModelStat{}
+			m.Models[t.Model] = st
+		}
+		st.OutputTokens += int64(t.OutputTokens)
+		st.DurationMs += t.DurationMs()
+		st.Turns++
+		if st.Turns > maxModelTurns {
+			st.OutputTokens /= 2
+			st.DurationMs /= 2
+			st.Turns /= 2
 		}
+		m.Sessions[sessionID] = t.EndMs
+		changed = true
+	}
+	if changed {
+		m.pruneSessions()
+	}
+	return changed
+}
 
-		if output, ok := usage["output_tokens"].(float64); ok && output > 0 {
-			return int(output)
+// pruneSessions 只保留最近 maxSessions 個 session 的累計進度
+func (m *modelAverages) pruneSessions() {
+	for len(m.Sessions) > maxSessions {
+		oldest, oldestMs := "", int64(0)
+		for id, ms := range m.Sessions {
+			if oldest == "" || ms < oldestMs || (ms == oldestMs && id < oldest) {
+				oldest, oldestMs = id, ms
+			}
 		}
+		delete(m.Sessions, oldest)
 	}
-	return 0
 }
 
-func getCachePath(sessionID string) string {
+// get 取得模型的平均輸出速度（tok/s），沒有資料時回傳 0
+func (m *modelAverages) get(model string) int {
+	st, ok := m.Models[model]
+	if !ok || st.DurationMs <= 0 {
+		return 0
+	}
+	return int(float64(st.OutputTokens) * 1000.0 / float64(st.DurationMs))
+}
+
+func getCachePath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return ""
 	}
-	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", fmt.Sprintf("speed-%s.json", sessionID))
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", "speed-models.json")
 }
 
-func loadMeasurement(sessionID string) *Measurement {
-	path := getCachePath(sessionID)
+func loadModelAverages() *modelAverages {
+	path := getCachePath()
 	if path == "" {
-		return nil
+		return newModelAverages()
 	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil
+		return newModelAverages()
 	}
 
-	var m Measurement
-	if err := json.Unmarshal(data, &m); err != nil {
-		return nil
+	averages := newModelAverages()
+	if err := json.Unmarshal(data, averages); err != nil || averages.Models == nil || averages.Sessions == nil {
+		return newModelAverages()
 	}
-
-	return &m
+	return averages
 }
 
-func saveMeasurement(sessionID string, m *Measurement) {
-	path := getCachePath(sessionID)
+// saveModelAverages 先寫暫存檔再改名，並行的 statusline 行程不會讀到寫到一半的檔案。
+// 同時寫入時後寫者覆蓋先寫者，但累計進度與總量在同一個檔案，被覆蓋的回合會在下次渲染時補上。
+func saveModelAverages(averages *modelAverages) {
+	path := getCachePath()
 	if path == "" {
 		return
 	}
@@ -123,18 +254,27 @@ func saveMeasurement(sessionID string, m *Measurement) {
 		return
 	}
 
-	data, err := json.Marshal(m)
+	data, err := json.Marshal(averages)
 	if err != nil {
 		return
 	}
 
-	_ = os.WriteFile(path, data, 0644)
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+	}
 }
 
-// Format 格式化速度顯示
+// Format 格式化速度顯示；有模型歷史平均時附上（如 "42 tok/s ⌀55"）
 func Format(info *SpeedInfo) string {
 	if info == nil {
 		return ""
 	}
+	if info.ModelAvg > 0 {
+		return fmt.Sprintf("%d tok/s ⌀%d", info.TokensPerSec, info.ModelAvg)
+	}
 	return fmt.Sprintf("%d tok/s", info.TokensPerSec)
 }
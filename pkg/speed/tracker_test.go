@@ -1,38 +1,142 @@
 package speed
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
-func TestExtractOutputTokens(t *testing.T) {
+func userLine(ts string) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"timestamp": ts,
+		"message":   map[string]interface{}{"role": "user", "content": "go"},
+	}}
+}
+
+func assistantLine(ts, id, model string, output float64) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"timestamp": ts,
+		"message": map[string]interface{}{
+			"id":    id,
+			"role":  "assistant",
+			"model": model,
+			"usage": map[string]interface{}{"output_tokens": output},
+		},
+	}}
+}
+
+func TestExtractTurns(t *testing.T) {
 	lines := []transcript.Line{
-		{Parsed: map[string]interface{}{
-			"message": map[string]interface{}{
-				"usage": map[string]interface{}{
-					"output_tokens": float64(500),
-				},
-			},
-		}},
-	}
-
-	result := extractOutputTokens(lines)
-	if result != 500 {
-		t.Fatalf("expected 500, got %d", result)
-		return
+		userLine("2026-01-01T00:00:00Z"),
+		// 同一則訊息拆成兩行：取最後一行的時間與 output_tokens
+		assistantLine("2026-01-01T00:00:02Z", "msg_1", "claude-sonnet-4-5", 10),
+		assistantLine("2026-01-01T00:00:10.500Z", "msg_1", "claude-sonnet-4-5", 420),
+		// 子代理的行不影響主對話的請求時間
+		{Parsed: map[string]interface{}{"isSidechain": true, "timestamp": "2026-01-01T00:00:11Z", "message": map[string]interface{}{"role": "user"}}},
+		userLine("2026-01-01T00:00:12Z"),
+		assistantLine("2026-01-01T00:00:16Z", "msg_2", "claude-sonnet-4-5", 200),
+	}
+
+	turns := ExtractTurns(lines)
+	if len(turns) != 2 {
+		t.Fatalf("expected 2 turns, got %d", len(turns))
+	}
+	if turns[0].OutputTokens != 420 || turns[0].DurationMs() != 10500 || !turns[0].Complete {
+		t.Errorf("unexpected first turn: %+v", turns[0])
+	}
+	if turns[1].DurationMs() != 4000 || turns[1].Complete {
+		t.Errorf("unexpected second turn: %+v", turns[1])
 	}
 }
 
-func TestExtractOutputTokensEmpty(t *testing.T) {
+func TestCalculateRollingAndModelAverage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
 	lines := []transcript.Line{
-		{Parsed: map[string]interface{}{"type": "user"}},
+		userLine("2026-01-01T00:00:00Z"),
+		assistantLine("2026-01-01T00:00:10Z", "msg_1", "claude-opus-4-1", 1000), // 100 tok/s
+		userLine("2026-01-01T00:00:20Z"),
+		assistantLine("2026-01-01T00:00:30Z", "msg_2", "claude-opus-4-1", 500), // 50 tok/s
+		userLine("2026-01-01T00:00:40Z"),
+		assistantLine("2026-01-01T00:00:50Z", "msg_3", "claude-opus-4-1", 300), // 30 tok/s，尚未完成
 	}
 
-	result := extractOutputTokens(lines)
-	if result != 0 {
-		t.Fatalf("expected 0, got %d", result)
-		return
+	// 視窗 2：最近兩個回合 (500+300)/20s
+	info := Calculate(lines, "s1", 2)
+	if info == nil || info.TokensPerSec != 40 {
+		t.Fatalf("expected 40 tok/s, got %+v", info)
+	}
+	// 模型平均只計入已完成的回合：(1000+500)/20s
+	if info.ModelAvg != 75 {
+		t.Errorf("expected model average 75, got %d", info.ModelAvg)
+	}
+
+	// 重複計算同一份 transcript 不會重複累加
+	if again := Calculate(lines, "s1", 2); again.ModelAvg != 75 {
+		t.Errorf("expected model average to stay 75, got %d", again.ModelAvg)
+	}
+	if got := Format(info); got != "40 tok/s ⌀75" {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
+
+func TestModelAverageConcurrentSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// session A 先渲染，其回合結束得比 session B 晚
+	sessionA := []transcript.Line{
+		userLine("2026-01-01T00:01:00Z"),
+		assistantLine("2026-01-01T00:01:10Z", "msg_a", "claude-opus-4-1", 1000), // 100 tok/s
+		userLine("2026-01-01T00:01:20Z"),
+	}
+	sessionB := []transcript.Line{
+		userLine("2026-01-01T00:00:00Z"),
+		assistantLine("2026-01-01T00:00:10Z", "msg_b", "claude-opus-4-1", 500), // 50 tok/s
+		userLine("2026-01-01T00:00:20Z"),
+	}
+
+	Calculate(sessionA, "a", 0)
+	info := Calculate(sessionB, "b", 0)
+	// B 較早結束的回合仍然計入：(1000+500)/20s
+	if info == nil || info.ModelAvg != 75 {
+		t.Fatalf("expected model average 75, got %+v", info)
+	}
+
+	// 快取檔以改名寫入，不留下暫存檔
+	entries, err := os.ReadDir(filepath.Dir(getCachePath()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "speed-models.json" {
+		t.Errorf("unexpected cache dir contents: %v", entries)
+	}
+}
+
+func TestModelAveragePrunesSessions(t *testing.T) {
+	m := newModelAverages()
+	for i := 0; i <= maxSessions; i++ {
+		turn := Turn{Model: "m", OutputTokens: 100, StartMs: 1, EndMs: int64(1000 + i), Complete: true}
+		m.add(fmt.Sprintf("s%03d", i), []Turn{turn})
+	}
+	if len(m.Sessions) != maxSessions {
+		t.Fatalf("expected %d sessions, got %d", maxSessions, len(m.Sessions))
+	}
+	if _, ok := m.Sessions["s000"]; ok {
+		t.Error("oldest session should be pruned")
+	}
+}
+
+func TestCalculateNoTimestamps(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	lines := []transcript.Line{
+		{Parsed: map[string]interface{}{"message": map[string]interface{}{"role": "user"}}},
+		{Parsed: map[string]interface{}{"message": map[string]interface{}{"id": "m", "role": "assistant", "usage": map[string]interface{}{"output_tokens": float64(500)}}}},
+	}
+	if info := Calculate(lines, "s1", 0); info != nil {
+		t.Errorf("expected nil without timestamps, got %+v", info)
 	}
 }
 
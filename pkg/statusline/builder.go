@@ -4,26 +4,29 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
-// 模型圖示和顏色
-var modelConfig = map[string][2]string{
-	"Opus":   {ColorGold, "💛"},
-	"Sonnet": {ColorCyan, "💠"},
-	"Haiku":  {ColorPink, "🌸"},
+// 模型圖示和顏色（顏色以指標引用，UsePalette 切換配色後仍有效）
+var modelConfig = map[string]struct {
+	color *string
+	icon  string
+}{
+	"Opus":   {&ColorGold, "💛"},
+	"Sonnet": {&ColorCyan, "💠"},
+	"Haiku":  {&ColorPink, "🌸"},
 }
 
 // FormatModel 格式化模型顯示
 func FormatModel(model string) string {
 	for key, config := range modelConfig {
 		if strings.Contains(model, key) {
-			color := config[0]
-			icon := config[1]
-			return fmt.Sprintf("%s%s %s%s", color, icon, model, ColorReset)
+			return fmt.Sprintf("%s%s %s%s", *config.color, config.icon, model, ColorReset)
 		}
 	}
 	return model
@@ -190,10 +193,7 @@ func formatUserMessage(message string) string {
 			break
 		}
 
-		line = strings.TrimSpace(line)
-		if len(line) > lineWidth {
-			line = line[:lineWidth-3] + "..."
-		}
+		line = Truncate(strings.TrimSpace(line), lineWidth, "...")
 
 		result = append(result, fmt.Sprintf("%s｜%s%s%s",
 			ColorReset, ColorGreen, line, ColorReset))
@@ -230,11 +230,13 @@ func (rl *RenderLine) String() string {
 	return strings.Join(rl.Parts, "")
 }
 
-// FormatToolsLine 格式化工具行
+// FormatToolsLine 格式化工具行。
+// 內含顏色（如執行過久的警告）的段落結束後恢復工具行的黃色。
 func FormatToolsLine(toolsStr string) string {
 	if toolsStr == "" {
 		return ""
 	}
+	toolsStr = strings.ReplaceAll(toolsStr, ColorReset, ColorReset+ColorYellow)
 	return fmt.Sprintf("%s%s%s", ColorYellow, toolsStr, ColorReset)
 }
 
@@ -271,6 +273,22 @@ func FormatSpeedDisplay(speedStr string) string {
 	return fmt.Sprintf(" %s%s%s", ColorDim, speedStr, ColorReset)
 }
 
+// FormatLatencyDisplay 格式化 API 延遲統計
+func FormatLatencyDisplay(latencyStr string) string {
+	if latencyStr == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %s⏱ %s%s", ColorDim, latencyStr, ColorReset)
+}
+
+// FormatToolUsageDisplay 格式化 session 工具使用統計
+func FormatToolUsageDisplay(usageStr string) string {
+	if usageStr == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %s%s%s", ColorDim, usageStr, ColorReset)
+}
+
 // FormatSessionNameDisplay 格式化 session 名稱
 func FormatSessionNameDisplay(name string) string {
 	if name == "" {
@@ -287,6 +305,42 @@ func FormatAutocompactDisplay(autocompactStr string) string {
 	return fmt.Sprintf(" %s%s%s", ColorRed, autocompactStr, ColorReset)
 }
 
+// HyperlinksEnabled 是否輸出 OSC 8 超連結，由 main 依設定與終端偵測結果設定
+var HyperlinksEnabled = true
+
+// Hyperlink 以 OSC 8 將文字包成可點擊的終端超連結；url 為空或停用超連結時原樣回傳。
+func Hyperlink(url, text string) string {
+	if url == "" || !HyperlinksEnabled {
+		return text
+	}
+	return "\033]8;;" + url + "\033\\" + text + osc8Close
+}
+
+// FileURL 將本機路徑轉為 file:// URL；line > 0 時附上 "#L<line>" 片段（iTerm2、VS Code 等會跳到該行）。
+// 主機名稱依 OSC 8 規範填入，讓遠端 SSH 的連結不會誤開本機檔案。
+func FileURL(path string, line int) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	host, _ := os.Hostname()
+	u := url.URL{Scheme: "file", Host: host, Path: filepath.ToSlash(path)}
+	if line > 0 {
+		u.Fragment = fmt.Sprintf("L%d", line)
+	}
+	return u.String()
+}
+
+// FormatPRDisplay 格式化 PR 顯示（有網址時為可點擊連結）
+func FormatPRDisplay(prStr, url string) string {
+	if prStr == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %s%s%s", ColorBlue, Hyperlink(url, prStr), ColorReset)
+}
+
 // FormatGitStatusDisplay 格式化 Git 狀態
 func FormatGitStatusDisplay(gitStatusStr string) string {
 	if gitStatusStr == "" {
@@ -328,6 +382,18 @@ func FormatCacheDisplay(cacheStr string, hitRate int) string {
 	return fmt.Sprintf(" %s%s%s", color, cacheStr, ColorReset)
 }
 
+// FormatCacheExpiryDisplay 格式化 prompt cache 過期倒數，warn 時以紅色顯示
+func FormatCacheExpiryDisplay(expiryStr string, warn bool) string {
+	if expiryStr == "" {
+		return ""
+	}
+	color := ColorDim
+	if warn {
+		color = ColorRed
+	}
+	return fmt.Sprintf(" %s%s%s", color, expiryStr, ColorReset)
+}
+
 // FormatCostColored 格式化 cost 顯示，依金額著色
 // <$5 預設色，≥$5 黃色，≥$10 紅色
 // sep 為分隔符（例如 " | "）
@@ -2,6 +2,7 @@ package statusline
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 )
@@ -225,3 +226,34 @@ func TestFormatCostColored(t *testing.T) {
 		})
 	}
 }
+
+func TestHyperlink(t *testing.T) {
+	orig := HyperlinksEnabled
+	defer func() { HyperlinksEnabled = orig }()
+
+	HyperlinksEnabled = true
+	if got := Hyperlink("https://example.com", "x"); got != "\033]8;;https://example.com\033\\x\033]8;;\033\\" {
+		t.Errorf("unexpected hyperlink %q", got)
+	}
+	if got := Hyperlink("", "x"); got != "x" {
+		t.Errorf("empty url should return text, got %q", got)
+	}
+
+	HyperlinksEnabled = false
+	if got := Hyperlink("https://example.com", "x"); got != "x" {
+		t.Errorf("disabled hyperlinks should return text, got %q", got)
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	host, _ := os.Hostname()
+	if got, want := FileURL("/tmp/my file.go", 12), "file://"+host+"/tmp/my%20file.go#L12"; got != want {
+		t.Errorf("FileURL = %q, want %q", got, want)
+	}
+	if got, want := FileURL("/tmp/a.go", 0), "file://"+host+"/tmp/a.go"; got != want {
+		t.Errorf("FileURL = %q, want %q", got, want)
+	}
+	if got := FileURL("", 3); got != "" {
+		t.Errorf("empty path should return empty URL, got %q", got)
+	}
+}
@@ -1,30 +1,134 @@
 package statusline
 
-// ANSI 顏色定義
+// 不隨背景改變的控制序列
 const (
-	ColorReset  = "\033[0m"
-	ColorGold   = "\033[38;2;195;158;83m"
-	ColorCyan   = "\033[38;2;118;170;185m"
-	ColorPink   = "\033[38;2;255;182;193m"
-	ColorGreen  = "\033[38;2;152;195;121m"
-	ColorGray   = "\033[38;2;64;64;64m"
-	ColorSilver = "\033[38;2;192;192;192m"
-
-	ColorCtxGreen = "\033[38;2;108;167;108m"
-	ColorCtxGold  = "\033[38;2;188;155;83m"
-	ColorCtxRed   = "\033[38;2;185;102;82m"
+	ColorReset = "\033[0m"
+	ColorDim   = "\033[2m"
+)
+
+// ANSI 顏色定義；預設為深色背景的配色，淺色背景由 UsePalette(LightPalette) 切換
+var (
+	ColorGold   = DarkPalette.Gold
+	ColorCyan   = DarkPalette.Cyan
+	ColorPink   = DarkPalette.Pink
+	ColorGreen  = DarkPalette.Green
+	ColorGray   = DarkPalette.Gray
+	ColorSilver = DarkPalette.Silver
+
+	ColorCtxGreen = DarkPalette.CtxGreen
+	ColorCtxGold  = DarkPalette.CtxGold
+	ColorCtxRed   = DarkPalette.CtxRed
 
 	// 新增顏色（claude-hud 風格）
-	ColorDim        = "\033[2m"
-	ColorYellow     = "\033[38;2;229;192;123m"
-	ColorMagenta    = "\033[38;2;198;120;221m"
-	ColorBlue       = "\033[38;2;97;175;239m"
-	ColorBrightBlue = "\033[38;2;130;170;255m"
-	ColorRed        = "\033[38;2;224;108;117m"
-	ColorWhite      = "\033[38;2;220;220;220m"
+	ColorYellow     = DarkPalette.Yellow
+	ColorMagenta    = DarkPalette.Magenta
+	ColorBlue       = DarkPalette.Blue
+	ColorBrightBlue = DarkPalette.BrightBlue
+	ColorRed        = DarkPalette.Red
+	ColorWhite      = DarkPalette.White
 
 	// API 配額顏色
-	ColorQuotaOk      = "\033[38;2;130;170;255m" // bright blue <75%
-	ColorQuotaWarning = "\033[38;2;198;120;221m" // bright magenta 75-90%
-	ColorQuotaCrit    = "\033[38;2;224;108;117m" // red 90%+
+	ColorQuotaOk      = DarkPalette.QuotaOk
+	ColorQuotaWarning = DarkPalette.QuotaWarning
+	ColorQuotaCrit    = DarkPalette.QuotaCrit
+
+	// ColorGradient context 進度條的 10 格漸層色（綠→黃→橙→紅）
+	ColorGradient = DarkPalette.Gradient
 )
+
+// Palette 一組前景色
+type Palette struct {
+	Gold, Cyan, Pink, Green, Gray, Silver string
+	CtxGreen, CtxGold, CtxRed             string
+	Yellow, Magenta, Blue, BrightBlue     string
+	Red, White                            string
+	QuotaOk, QuotaWarning, QuotaCrit      string
+	Gradient                              [10]string
+}
+
+// DarkPalette 深色背景的配色（預設）
+var DarkPalette = Palette{
+	Gold:   "\033[38;2;195;158;83m",
+	Cyan:   "\033[38;2;118;170;185m",
+	Pink:   "\033[38;2;255;182;193m",
+	Green:  "\033[38;2;152;195;121m",
+	Gray:   "\033[38;2;98;98;98m",
+	Silver: "\033[38;2;192;192;192m",
+
+	CtxGreen: "\033[38;2;108;167;108m",
+	CtxGold:  "\033[38;2;188;155;83m",
+	CtxRed:   "\033[38;2;185;102;82m",
+
+	Yellow:     "\033[38;2;229;192;123m",
+	Magenta:    "\033[38;2;198;120;221m",
+	Blue:       "\033[38;2;97;175;239m",
+	BrightBlue: "\033[38;2;130;170;255m",
+	Red:        "\033[38;2;224;108;117m",
+	White:      "\033[38;2;220;220;220m",
+
+	QuotaOk:      "\033[38;2;130;170;255m", // bright blue <75%
+	QuotaWarning: "\033[38;2;198;120;221m", // bright magenta 75-90%
+	QuotaCrit:    "\033[38;2;224;108;117m", // red 90%+
+
+	Gradient: [10]string{
+		"\033[38;2;76;175;80m",  // green
+		"\033[38;2;108;175;72m", // green-yellow
+		"\033[38;2;139;175;64m", // yellow-green
+		"\033[38;2;171;175;56m", // yellow
+		"\033[38;2;202;165;48m", // gold
+		"\033[38;2;224;150;40m", // gold-orange
+		"\033[38;2;234;130;36m", // orange
+		"\033[38;2;244;110;32m", // orange-red
+		"\033[38;2;244;80;30m",  // red-orange
+		"\033[38;2;244;67;54m",  // red
+	},
+}
+
+// LightPalette 淺色背景的配色：降低亮度、提高飽和度，並把灰／白改為深色
+var LightPalette = Palette{
+	Gold:   "\033[38;2;150;110;30m",
+	Cyan:   "\033[38;2;30;120;140m",
+	Pink:   "\033[38;2;190;70;110m",
+	Green:  "\033[38;2;60;130;50m",
+	Gray:   "\033[38;2;190;190;190m",
+	Silver: "\033[38;2;110;110;110m",
+
+	CtxGreen: "\033[38;2;50;130;60m",
+	CtxGold:  "\033[38;2;160;115;20m",
+	CtxRed:   "\033[38;2;180;60;50m",
+
+	Yellow:     "\033[38;2;165;120;20m",
+	Magenta:    "\033[38;2;150;60;170m",
+	Blue:       "\033[38;2;30;100;200m",
+	BrightBlue: "\033[38;2;50;90;210m",
+	Red:        "\033[38;2;190;50;60m",
+	White:      "\033[38;2;60;60;60m",
+
+	QuotaOk:      "\033[38;2;50;90;210m",
+	QuotaWarning: "\033[38;2;150;60;170m",
+	QuotaCrit:    "\033[38;2;190;50;60m",
+
+	Gradient: [10]string{
+		"\033[38;2;46;125;50m",
+		"\033[38;2;85;130;40m",
+		"\033[38;2;115;130;30m",
+		"\033[38;2;145;130;20m",
+		"\033[38;2;170;120;15m",
+		"\033[38;2;190;105;10m",
+		"\033[38;2;200;90;10m",
+		"\033[38;2;205;75;15m",
+		"\033[38;2;205;60;20m",
+		"\033[38;2;198;40;40m",
+	},
+}
+
+// UsePalette 切換所有 Color* 變數為指定配色（由 main 依終端背景在渲染前呼叫）
+func UsePalette(p Palette) {
+	ColorGold, ColorCyan, ColorPink, ColorGreen = p.Gold, p.Cyan, p.Pink, p.Green
+	ColorGray, ColorSilver = p.Gray, p.Silver
+	ColorCtxGreen, ColorCtxGold, ColorCtxRed = p.CtxGreen, p.CtxGold, p.CtxRed
+	ColorYellow, ColorMagenta, ColorBlue, ColorBrightBlue = p.Yellow, p.Magenta, p.Blue, p.BrightBlue
+	ColorRed, ColorWhite = p.Red, p.White
+	ColorQuotaOk, ColorQuotaWarning, ColorQuotaCrit = p.QuotaOk, p.QuotaWarning, p.QuotaCrit
+	ColorGradient = p.Gradient
+}
@@ -0,0 +1,23 @@
+package statusline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsePalette(t *testing.T) {
+	defer UsePalette(DarkPalette)
+
+	UsePalette(LightPalette)
+	if ColorGray != LightPalette.Gray || ColorWhite != LightPalette.White || ColorGradient != LightPalette.Gradient {
+		t.Fatal("UsePalette should switch all colors to the light palette")
+	}
+	if got := FormatModel("Opus 4.6"); !strings.HasPrefix(got, LightPalette.Gold) {
+		t.Errorf("FormatModel should use the active palette, got %q", got)
+	}
+
+	UsePalette(DarkPalette)
+	if got := FormatModel("Opus 4.6"); !strings.HasPrefix(got, DarkPalette.Gold) {
+		t.Errorf("FormatModel should follow palette switch back, got %q", got)
+	}
+}
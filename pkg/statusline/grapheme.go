@@ -0,0 +1,105 @@
+package statusline
+
+import "unicode"
+
+const (
+	zeroWidthJoiner = '\u200d'
+	variationText   = '\ufe0e' // VS15：要求文字樣式（1 欄）
+	variationEmoji  = '\ufe0f' // VS16：要求 emoji 樣式（2 欄）
+)
+
+// nextGrapheme 回傳從 runes[i] 開始的字素叢集（使用者感知的單一字元）的結束位置。
+// 涵蓋終端顯示常見的情形：組合字元、變體選擇符、膚色修飾、ZWJ emoji 序列、
+// 國旗（成對的 regional indicator）、tag 序列與 keycap；並非完整的 UAX #29 實作。
+func nextGrapheme(runes []rune, i int) int {
+	j := i + 1
+	if isRegionalIndicator(runes[i]) && j < len(runes) && isRegionalIndicator(runes[j]) {
+		j++
+	}
+	for j < len(runes) {
+		r := runes[j]
+		switch {
+		case isExtend(r):
+			j++
+		case r == zeroWidthJoiner:
+			j++
+			// ZWJ 連接下一個字元（👨‍💻）；後面是 escape 或控制字元時不合併
+			if j < len(runes) && runes[j] >= 0x20 && runes[j] != 0x7F {
+				j++
+			}
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+// clusterWidth 回傳字素叢集的顯示寬度：以第一個字元為準，
+// VS16 變體選擇符與國旗固定 2 欄，VS15 固定 1 欄
+func clusterWidth(cluster []rune) int {
+	w := runeWidth(cluster[0])
+	if len(cluster) == 1 {
+		return w
+	}
+	if isRegionalIndicator(cluster[0]) && isRegionalIndicator(cluster[1]) {
+		return 2
+	}
+	for _, r := range cluster[1:] {
+		switch r {
+		case variationEmoji:
+			return 2
+		case variationText:
+			return 1
+		}
+	}
+	return w
+}
+
+// isExtend 判斷字元是否附加在前一個字元上、不單獨佔欄位
+func isExtend(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // 變體選擇符
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // 膚色修飾
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag 序列（英格蘭等地區旗幟）
+		return true
+	case r >= 0xE0100 && r <= 0xE01EF: // 變體選擇符補充
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator 判斷是否為國旗用的 regional indicator 字母
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// Truncate 依顯示寬度截斷純文字，超出 maxWidth 時以 tail（如 "…" 或 "..."）結尾，結果不超過 maxWidth 欄。
+// 以字素叢集為單位切割，不會拆開多位元組字元、emoji 序列或組合字元；
+// 含 escape sequence 的字串請用 TruncateVisible。
+func Truncate(s string, maxWidth int, tail string) string {
+	if VisibleWidth(s) <= maxWidth {
+		return s
+	}
+	limit := maxWidth - VisibleWidth(tail)
+	if limit < 0 {
+		return ""
+	}
+
+	runes := []rune(s)
+	width := 0
+	end := 0
+	for end < len(runes) {
+		next := nextGrapheme(runes, end)
+		w := clusterWidth(runes[end:next])
+		if width+w > limit {
+			break
+		}
+		width += w
+		end = next
+	}
+	return string(runes[:end]) + tail
+}
@@ -0,0 +1,74 @@
+package statusline
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestVisibleWidthGraphemes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"cjk", "中文測試", 8},
+		{"zwj family", "👨‍👩‍👧", 2},
+		{"zwj technologist", "👩‍💻 dev", 6},
+		{"skin tone", "👍🏽", 2},
+		{"flag", "🇹🇼", 2},
+		{"single regional indicator", "🇹", 1},
+		{"emoji presentation selector", "⚠️", 2},
+		{"text presentation selector", "⚠︎", 1},
+		{"keycap", "1️⃣", 2},
+		{"combining accent", "e\u0301", 1},
+		{"tag sequence", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VisibleWidth(tt.input); got != tt.want {
+				t.Errorf("VisibleWidth(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxWidth int
+		tail     string
+		want     string
+	}{
+		{"fits", "hello", 5, "…", "hello"},
+		{"ascii", "hello world", 8, "...", "hello..."},
+		{"cjk", "修正登入流程的錯誤", 9, "…", "修正登入…"},
+		{"cjk odd boundary", "修正登入流程", 8, "...", "修正..."},
+		{"zwj kept whole", "ab👨‍👩‍👧cd", 5, "…", "ab👨‍👩‍👧…"},
+		{"zwj dropped whole", "abc👨‍👩‍👧d", 5, "…", "abc…"},
+		{"combining kept with base", "cafe\u0301 au lait", 5, "…", "cafe\u0301…"},
+		{"flag not split", "a🇹🇼b🇯🇵", 4, "…", "a🇹🇼…"},
+		{"tail wider than width", "hello", 2, "...", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.input, tt.maxWidth, tt.tail)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.input, tt.maxWidth, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) produced invalid UTF-8", tt.input, tt.maxWidth)
+			}
+			if w := VisibleWidth(got); w > tt.maxWidth {
+				t.Errorf("Truncate(%q, %d) width = %d, exceeds max", tt.input, tt.maxWidth, w)
+			}
+		})
+	}
+}
+
+func TestTruncateVisibleGraphemes(t *testing.T) {
+	got := TruncateVisible("\033[32m👨‍👩‍👧 family\033[0m", 3)
+	if got != "\033[32m👨‍👩‍👧…"+ColorReset {
+		t.Errorf("expected ZWJ sequence kept whole, got %q", got)
+	}
+}
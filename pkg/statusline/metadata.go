@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
@@ -12,9 +13,10 @@ import (
 
 // ConfigCounts 代表配置檔案統計
 type ConfigCounts struct {
-	ClaudeMD   int
-	MCPServers int
-	Hooks      int
+	ClaudeMD       int
+	MCPServers     int
+	MCPServerNames []string // 使用者與專案 settings 中的 MCP server 名稱（去重、排序）
+	Hooks          int
 }
 
 // ExtractSessionName 從 transcript 行中提取 session 名稱
@@ -66,17 +68,36 @@ func CountConfigFiles(projectDir string) *ConfigCounts {
 
 	// 從 Claude Code settings 讀取 MCP 和 hooks
 	settingsPath := filepath.Join(homeDir, ".claude", "settings.json")
-	counts.MCPServers, counts.Hooks = parseSettings(settingsPath)
+	userMCP, userHooks := parseSettings(settingsPath)
 
 	// 也檢查專案級別的 settings
 	projectSettingsPath := filepath.Join(projectDir, ".claude", "settings.json")
 	projectMCP, projectHooks := parseSettings(projectSettingsPath)
-	counts.MCPServers += projectMCP
-	counts.Hooks += projectHooks
+
+	// 同一個 server 在使用者與專案設定中都有定義時只算一個
+	counts.MCPServerNames = mergeNames(userMCP, projectMCP)
+	counts.MCPServers = len(counts.MCPServerNames)
+	counts.Hooks = userHooks + projectHooks
 
 	return counts
 }
 
+// mergeNames 合併多組名稱並去重排序
+func mergeNames(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, list := range lists {
+		for _, n := range list {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func countClaudeMD(projectDir string) int {
 	count := 0
 
@@ -100,20 +121,23 @@ func countClaudeMD(projectDir string) int {
 	return count
 }
 
-func parseSettings(path string) (mcpCount, hookCount int) {
+// parseSettings 讀取 settings.json 中的 MCP server 名稱與 hook 數量
+func parseSettings(path string) (mcpServers []string, hookCount int) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return 0, 0
+		return nil, 0
 	}
 
 	var settings map[string]interface{}
 	if err := json.Unmarshal(data, &settings); err != nil {
-		return 0, 0
+		return nil, 0
 	}
 
-	// 計算 MCP servers
+	// 收集 MCP servers
 	if mcps, ok := settings["mcpServers"].(map[string]interface{}); ok {
-		mcpCount = len(mcps)
+		for name := range mcps {
+			mcpServers = append(mcpServers, name)
+		}
 	}
 
 	// 計算 hooks
@@ -125,7 +149,7 @@ func parseSettings(path string) (mcpCount, hookCount int) {
 		}
 	}
 
-	return mcpCount, hookCount
+	return mcpServers, hookCount
 }
 
 // FormatConfigCounts 格式化配置統計為顯示字串
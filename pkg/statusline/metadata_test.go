@@ -0,0 +1,34 @@
+package statusline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountConfigFilesMCPServerNames(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("HOME", home)
+
+	write := func(dir, content string) {
+		if err := os.MkdirAll(filepath.Join(dir, ".claude"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".claude", "settings.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(home, `{"mcpServers":{"github":{},"slack":{}}}`)
+	write(project, `{"mcpServers":{"github":{},"db":{}},"hooks":{"Stop":[{}]}}`)
+
+	counts := CountConfigFiles(project)
+	// github 在兩處都有定義，只算一次
+	if counts.MCPServers != 3 || counts.Hooks != 1 {
+		t.Errorf("unexpected counts: %+v", counts)
+	}
+	if got := strings.Join(counts.MCPServerNames, ","); got != "db,github,slack" {
+		t.Errorf("expected merged server names db,github,slack, got %q", got)
+	}
+}
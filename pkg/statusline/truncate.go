@@ -14,28 +14,100 @@ type Segment struct {
 }
 
 // VisibleWidth 計算字串的可見欄位寬度，
-// 跳過 ANSI escape sequences，emoji/寬字元算 2 欄。
+// 跳過 ANSI escape sequences（CSI 與 OSC，如 OSC 8 超連結），以字素叢集為單位計算，emoji/寬字元算 2 欄。
 func VisibleWidth(s string) int {
 	width := 0
-	inEscape := false
 	runes := []rune(s)
-	for i := 0; i < len(runes); i++ {
-		r := runes[i]
-		if inEscape {
-			// CSI 序列以字母結束（@-~，即 0x40-0x7E）
-			if r >= 0x40 && r <= 0x7E {
-				inEscape = false
-			}
+	for i := 0; i < len(runes); {
+		if end, ok := escapeEnd(runes, i); ok {
+			i = end
 			continue
 		}
-		if r == '\033' && i+1 < len(runes) && runes[i+1] == '[' {
-			inEscape = true
-			i++ // 跳過 '['
+		next := nextGrapheme(runes, i)
+		width += clusterWidth(runes[i:next])
+		i = next
+	}
+	return width
+}
+
+// escapeEnd 若 runes[i] 開始一個 CSI（ESC [）或 OSC（ESC ]）序列，回傳序列結束後的位置。
+// CSI 以 0x40-0x7E 的字元結束；OSC 以 BEL 或 ST（ESC \）結束；未結束的序列延伸到字串結尾。
+func escapeEnd(runes []rune, i int) (int, bool) {
+	if runes[i] != '\033' || i+1 >= len(runes) {
+		return 0, false
+	}
+	switch runes[i+1] {
+	case '[':
+		for j := i + 2; j < len(runes); j++ {
+			if runes[j] >= 0x40 && runes[j] <= 0x7E {
+				return j + 1, true
+			}
+		}
+	case ']':
+		for j := i + 2; j < len(runes); j++ {
+			if runes[j] == '\a' {
+				return j + 1, true
+			}
+			if runes[j] == '\033' && j+1 < len(runes) && runes[j+1] == '\\' {
+				return j + 2, true
+			}
+		}
+	default:
+		return 0, false
+	}
+	return len(runes), true
+}
+
+// osc8Close 結束 OSC 8 超連結的序列
+const osc8Close = "\033]8;;\033\\"
+
+// isHyperlinkSeq 判斷 OSC 序列是否為 OSC 8 超連結，並回傳是否開啟連結（URI 非空）
+func isHyperlinkSeq(seq string) (isLink, opens bool) {
+	body, ok := strings.CutPrefix(seq, "\033]8;")
+	if !ok {
+		return false, false
+	}
+	body = strings.TrimSuffix(strings.TrimSuffix(body, "\a"), "\033\\")
+	_, uri, _ := strings.Cut(body, ";")
+	return true, uri != ""
+}
+
+// TruncateVisible 將字串截斷至 maxWidth 欄以內，超出時以 "…" 結尾。
+// escape sequences 不計寬度並完整保留（不會從中間切斷）；截斷時關閉尚未結束的 OSC 8 超連結並重設顏色。
+func TruncateVisible(s string, maxWidth int) string {
+	if maxWidth <= 0 || VisibleWidth(s) <= maxWidth {
+		return s
+	}
+
+	var sb strings.Builder
+	width := 0
+	linkOpen := false
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if end, ok := escapeEnd(runes, i); ok {
+			seq := string(runes[i:end])
+			if isLink, opens := isHyperlinkSeq(seq); isLink {
+				linkOpen = opens
+			}
+			sb.WriteString(seq)
+			i = end
 			continue
 		}
-		width += runeWidth(r)
+		next := nextGrapheme(runes, i)
+		w := clusterWidth(runes[i:next])
+		if width+w > maxWidth-1 {
+			break
+		}
+		sb.WriteString(string(runes[i:next]))
+		width += w
+		i = next
 	}
-	return width
+	if linkOpen {
+		sb.WriteString(osc8Close)
+	}
+	sb.WriteString("…")
+	sb.WriteString(ColorReset)
+	return sb.String()
 }
 
 // runeWidth 回傳單一 rune 的顯示寬度。
@@ -87,6 +159,8 @@ func isWide(r rune) bool {
 		return true
 	case r >= 0x1F300 && r <= 0x1F9FF: // Misc Symbols & Emoji (📂 💛 💰 ⚡ 🌸 💠 🔇 💬 ⚠ etc.)
 		return true
+	case r >= 0x1FA70 && r <= 0x1FAFF: // Symbols & Pictographs Extended-A (🪄 🫡 etc.)
+		return true
 	case r >= 0x20000 && r <= 0x2FFFD: // CJK Extension B+
 		return true
 	case r >= 0x30000 && r <= 0x3FFFD: // CJK Extension G+
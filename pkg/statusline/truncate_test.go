@@ -26,6 +26,8 @@ func TestVisibleWidth(t *testing.T) {
 			"\033[38;2;76;175;80m█\033[0m\033[38;2;64;64;64m░░░░░░░░░\033[0m",
 			10},
 		{"model display", "\033[0m[💛 Opus 4.6]", 13},
+		{"osc8 hyperlink ST", "\033]8;;https://github.com/o/r/pull/1\033\\PR #1\033]8;;\033\\", 5},
+		{"osc8 hyperlink BEL", "\033]8;;https://example.com\aPR #1\033]8;;\a", 5},
 	}
 
 	for _, tt := range tests {
@@ -328,3 +330,33 @@ func TestTruncateLineWithAnsiColors(t *testing.T) {
 		t.Errorf("narrow width should drop cost segment, got %q", narrow)
 	}
 }
+
+func TestTruncateVisible(t *testing.T) {
+	if got := TruncateVisible("short", 10); got != "short" {
+		t.Errorf("expected no truncation, got %q", got)
+	}
+
+	got := TruncateVisible("\033[33mhello world\033[0m", 6)
+	if VisibleWidth(got) != 6 {
+		t.Errorf("expected width 6, got %d (%q)", VisibleWidth(got), got)
+	}
+	if !strings.HasPrefix(got, "\033[33mhello…") {
+		t.Errorf("expected colored prefix kept, got %q", got)
+	}
+
+	// 截斷點落在超連結文字中：序列不可切斷，且要關閉連結
+	link := "\033]8;;file:///tmp/a.go\033\\a.go:12\033]8;;\033\\ more"
+	got = TruncateVisible(link, 4)
+	if !strings.HasPrefix(got, "\033]8;;file:///tmp/a.go\033\\a.g") {
+		t.Errorf("expected hyperlink opener preserved, got %q", got)
+	}
+	if !strings.Contains(got, "a.g\033]8;;\033\\…") {
+		t.Errorf("expected hyperlink closed before ellipsis, got %q", got)
+	}
+
+	// 寬字元不可超出寬度
+	got = TruncateVisible("📂📂📂", 4)
+	if VisibleWidth(got) > 4 {
+		t.Errorf("expected width <= 4, got %d (%q)", VisibleWidth(got), got)
+	}
+}
@@ -0,0 +1,332 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Background 終端背景的明暗
+type Background int
+
+const (
+	// BackgroundDark 深色背景（預設）
+	BackgroundDark Background = iota
+	// BackgroundLight 淺色背景
+	BackgroundLight
+)
+
+// String 回傳 "dark" 或 "light"
+func (b Background) String() string {
+	if b == BackgroundLight {
+		return "light"
+	}
+	return "dark"
+}
+
+// BackgroundEnv 明確指定背景的環境變數（"light" 或 "dark"）
+const BackgroundEnv = "CLAUDE_STATUSLINE_BACKGROUND"
+
+// oscTimeout OSC 11 查詢等待終端回應的上限
+const oscTimeout = 150 * time.Millisecond
+
+// backgroundCacheTTL 快取的偵測結果有效時間；過期後重新查詢，以跟上終端切換主題
+const backgroundCacheTTL = 10 * time.Minute
+
+// BackgroundResolution 背景偵測結果
+type BackgroundResolution struct {
+	Background Background
+	Source     string  // config、環境變數、COLORFGBG、osc11 或 default
+	Luminance  float64 // OSC 11 取得的背景亮度（0-1）；其他來源為 -1
+	Cached     bool    // 結果來自先前查詢的快取
+	Err        error   // OSC 11 查詢失敗的原因（供除錯輸出）
+}
+
+// String 說明背景由哪個來源決定
+func (r BackgroundResolution) String() string {
+	s := fmt.Sprintf("background=%s source=%s", r.Background, r.Source)
+	if r.Luminance >= 0 {
+		s += fmt.Sprintf(" luminance=%.2f", r.Luminance)
+	}
+	if r.Cached {
+		s += " (cached)"
+	}
+	if r.Err != nil {
+		s += fmt.Sprintf(" (osc11: %v)", r.Err)
+	}
+	return s
+}
+
+// BackgroundQuery 啟用 OSC 11 查詢的設定值
+const BackgroundQuery = "query"
+
+// ResolveBackground 決定終端背景明暗：設定檔 → CLAUDE_STATUSLINE_BACKGROUND → COLORFGBG → 深色。
+// configured 為 "light" 或 "dark" 時直接採用；"auto" 或空字串時只讀環境變數。
+// configured 為 "query" 時，環境變數無法判斷才以 OSC 11 詢問終端（此終端 session 的快取 → 查詢）。
+// 查詢會暫時改變終端模式並寫入 Claude Code 正在讀取的終端，回應可能被當成輸入，因此需明確啟用；
+// 查詢（含失敗）的結果依終端 session 快取 backgroundCacheTTL，避免每次渲染都查詢。
+func ResolveBackground(configured string) BackgroundResolution {
+	if b, ok := parseBackground(configured); ok {
+		return BackgroundResolution{Background: b, Source: "config", Luminance: -1}
+	}
+	if b, ok := parseBackground(os.Getenv(BackgroundEnv)); ok {
+		return BackgroundResolution{Background: b, Source: BackgroundEnv, Luminance: -1}
+	}
+	if b, ok := colorFGBGBackground(os.Getenv("COLORFGBG")); ok {
+		return BackgroundResolution{Background: b, Source: "COLORFGBG", Luminance: -1}
+	}
+	if !strings.EqualFold(strings.TrimSpace(configured), BackgroundQuery) {
+		return BackgroundResolution{Background: BackgroundDark, Source: "default", Luminance: -1}
+	}
+
+	key := sessionKey()
+	cache := loadBackgroundCache()
+	if entry, ok := cache[key]; ok && time.Since(time.Unix(entry.CheckedAt, 0)) < backgroundCacheTTL {
+		r := entry.resolution()
+		r.Cached = true
+		return r
+	}
+
+	r := BackgroundResolution{Background: BackgroundDark, Source: "default", Luminance: -1}
+	if lum, err := queryBackgroundLuminance(); err == nil {
+		r.Source, r.Luminance = "osc11", lum
+		if lum > 0.5 {
+			r.Background = BackgroundLight
+		}
+	} else {
+		r.Err = err
+	}
+	cache[key] = newBackgroundEntry(r)
+	saveBackgroundCache(cache)
+	return r
+}
+
+// parseBackground 解析 "light" / "dark" 設定值
+func parseBackground(s string) (Background, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "light":
+		return BackgroundLight, true
+	case "dark":
+		return BackgroundDark, true
+	}
+	return BackgroundDark, false
+}
+
+// colorFGBGBackground 解析 rxvt 系終端設定的 COLORFGBG（"fg;bg" 或 "fg;default;bg"）。
+// 背景為 ANSI 7（白）或 9-15（亮色）時視為淺色；"default" 等無法判斷的值回傳 false。
+func colorFGBGBackground(v string) (Background, bool) {
+	if v == "" {
+		return BackgroundDark, false
+	}
+	fields := strings.Split(v, ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return BackgroundDark, false
+	}
+	if bg == 7 || bg >= 9 {
+		return BackgroundLight, true
+	}
+	return BackgroundDark, true
+}
+
+// queryBackgroundLuminance 以 OSC 11 詢問終端背景色並回傳亮度。
+// 先試 /dev/tty；statusline 由 Claude Code 以 pipe 啟動而沒有控制終端時，改用父行程的終端。
+func queryBackgroundLuminance() (float64, error) {
+	if os.Getenv("TERM") == "dumb" {
+		return 0, errors.New("dumb terminal")
+	}
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		tty, perr := parentTTY()
+		if perr != nil {
+			return 0, perr
+		}
+		if f, err = os.OpenFile(tty, os.O_RDWR|syscall.O_NOCTTY, 0); err != nil {
+			return 0, err
+		}
+	}
+	defer f.Close()
+
+	resp, err := queryOSC11(f.Fd(), oscTimeout)
+	if err != nil {
+		return 0, err
+	}
+	r, g, b, err := parseOSC11(resp)
+	if err != nil {
+		return 0, err
+	}
+	return luminance(r, g, b), nil
+}
+
+// queryOSC11 暫時關閉終端的 canonical 與 echo 模式，送出 OSC 11 查詢，讀取回應直到逾時。
+// 查詢後緊接著送出 DA1（ESC [ c）：幾乎所有終端都會回應 DA1，
+// 若先收到 DA1 回應代表終端不支援 OSC 11，不必等到逾時。
+func queryOSC11(fd uintptr, timeout time.Duration) (string, error) {
+	var old syscall.Termios
+	if err := termiosIoctl(fd, ioctlGetTermios, &old); err != nil {
+		return "", err
+	}
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1 // read 最多等待 0.1 秒
+	if err := termiosIoctl(fd, ioctlSetTermios, &raw); err != nil {
+		return "", err
+	}
+	defer func() { _ = termiosIoctl(fd, ioctlSetTermios, &old) }()
+
+	if _, err := syscall.Write(int(fd), []byte("\033]11;?\033\\\033[c")); err != nil {
+		return "", err
+	}
+
+	var resp []byte
+	buf := make([]byte, 256)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		n, err := syscall.Read(int(fd), buf)
+		if err != nil && err != syscall.EINTR && err != syscall.EAGAIN {
+			return "", err
+		}
+		resp = append(resp, buf[:max(n, 0)]...)
+		// DA1 回應（ESC [ ? ... c）一定在 OSC 11 回應之後
+		if i := bytes.Index(resp, []byte("\033[?")); i >= 0 && bytes.IndexByte(resp[i:], 'c') >= 0 {
+			break
+		}
+	}
+	if !bytes.Contains(resp, []byte("\033]11;")) {
+		return "", errors.New("no OSC 11 response")
+	}
+	return string(resp), nil
+}
+
+// termiosIoctl 讀取或設定終端的 termios
+func termiosIoctl(fd uintptr, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// parseOSC11 解析 OSC 11 回應（ESC ] 11 ; rgb:RRRR/GGGG/BBBB，以 BEL 或 ST 結尾），
+// 回傳 0-1 的 RGB。每個分量為 1-4 位十六進位數字。
+func parseOSC11(resp string) (r, g, b float64, err error) {
+	i := strings.Index(resp, "\033]11;")
+	if i < 0 {
+		return 0, 0, 0, errors.New("no OSC 11 response")
+	}
+	body := resp[i+len("\033]11;"):]
+	if end := strings.IndexAny(body, "\a\033"); end >= 0 {
+		body = body[:end]
+	}
+	spec, ok := strings.CutPrefix(body, "rgb:")
+	if !ok {
+		spec, ok = strings.CutPrefix(body, "rgba:")
+	}
+	parts := strings.Split(spec, "/")
+	if !ok || len(parts) < 3 {
+		return 0, 0, 0, fmt.Errorf("unsupported color %q", body)
+	}
+	var rgb [3]float64
+	for j := range rgb {
+		p := parts[j]
+		v, perr := strconv.ParseUint(p, 16, 16)
+		if perr != nil || len(p) == 0 || len(p) > 4 {
+			return 0, 0, 0, fmt.Errorf("unsupported color %q", body)
+		}
+		rgb[j] = float64(v) / float64(uint64(1)<<(4*len(p))-1)
+	}
+	return rgb[0], rgb[1], rgb[2], nil
+}
+
+// luminance 計算 0-1 的 RGB 的相對亮度（Rec. 709 係數）
+func luminance(r, g, b float64) float64 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// sessionKey 識別目前的終端 session（分頁、pane 或視窗），作為快取 key。
+// 優先使用終端設定的 session 環境變數，其次是父行程的控制終端。
+func sessionKey() string {
+	for _, k := range []string{"TMUX_PANE", "WEZTERM_PANE", "KITTY_WINDOW_ID", "ITERM_SESSION_ID", "TERM_SESSION_ID", "WT_SESSION", "WINDOWID"} {
+		if v := os.Getenv(k); v != "" {
+			return k + "=" + v
+		}
+	}
+	if tty, err := parentTTY(); err == nil {
+		return tty
+	}
+	return "default"
+}
+
+// backgroundEntry 快取的單一終端 session 偵測結果
+type backgroundEntry struct {
+	Background string  `json:"background"`
+	Source     string  `json:"source"`
+	Luminance  float64 `json:"luminance"`
+	CheckedAt  int64   `json:"checked_at"`
+}
+
+func newBackgroundEntry(r BackgroundResolution) backgroundEntry {
+	return backgroundEntry{Background: r.Background.String(), Source: r.Source, Luminance: r.Luminance, CheckedAt: time.Now().Unix()}
+}
+
+func (e backgroundEntry) resolution() BackgroundResolution {
+	b, _ := parseBackground(e.Background)
+	return BackgroundResolution{Background: b, Source: e.Source, Luminance: e.Luminance}
+}
+
+// maxBackgroundEntries 快取保留的終端 session 數上限（超過時清除過期項目）
+const maxBackgroundEntries = 64
+
+func getBackgroundCachePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", "background.json")
+}
+
+func loadBackgroundCache() map[string]backgroundEntry {
+	cache := make(map[string]backgroundEntry)
+	path := getBackgroundCachePath()
+	if path == "" {
+		return cache
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil || cache == nil {
+		return make(map[string]backgroundEntry)
+	}
+	return cache
+}
+
+func saveBackgroundCache(cache map[string]backgroundEntry) {
+	path := getBackgroundCachePath()
+	if path == "" {
+		return
+	}
+	if len(cache) > maxBackgroundEntries {
+		for k, e := range cache {
+			if time.Since(time.Unix(e.CheckedAt, 0)) >= backgroundCacheTTL {
+				delete(cache, k)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644)
+}
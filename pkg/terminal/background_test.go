@@ -0,0 +1,116 @@
+package terminal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseOSC11(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    string
+		r, g, b float64
+		wantErr bool
+	}{
+		{"16-bit ST", "\033]11;rgb:ffff/ffff/ffff\033\\\033[?62;c", 1, 1, 1, false},
+		{"16-bit BEL", "\033]11;rgb:1e1e/1e1e/2e2e\a", 0x1e1e / 65535.0, 0x1e1e / 65535.0, 0x2e2e / 65535.0, false},
+		{"8-bit", "\033]11;rgb:fd/f6/e3\033\\", 0xfd / 255.0, 0xf6 / 255.0, 0xe3 / 255.0, false},
+		{"rgba", "\033]11;rgba:0000/0000/0000/ffff\033\\", 0, 0, 0, false},
+		{"leading noise", "x\033]11;rgb:ff/00/00\a", 1, 0, 0, false},
+		{"da1 only", "\033[?62;22c", 0, 0, 0, true},
+		{"unknown format", "\033]11;#ffffff\a", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b, err := parseOSC11(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOSC11(%q) err = %v, wantErr %v", tt.resp, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, c := range [][2]float64{{r, tt.r}, {g, tt.g}, {b, tt.b}} {
+				if math.Abs(c[0]-c[1]) > 1e-6 {
+					t.Errorf("parseOSC11(%q) = (%v, %v, %v), want (%v, %v, %v)", tt.resp, r, g, b, tt.r, tt.g, tt.b)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLuminance(t *testing.T) {
+	if l := luminance(1, 1, 1); math.Abs(l-1) > 1e-9 {
+		t.Errorf("white luminance = %v, want 1", l)
+	}
+	// Solarized Light 背景 #fdf6e3 為淺色，Dracula #282a36 為深色
+	if l := luminance(0xfd/255.0, 0xf6/255.0, 0xe3/255.0); l <= 0.5 {
+		t.Errorf("solarized light luminance = %v, want > 0.5", l)
+	}
+	if l := luminance(0x28/255.0, 0x2a/255.0, 0x36/255.0); l >= 0.5 {
+		t.Errorf("dracula luminance = %v, want < 0.5", l)
+	}
+}
+
+func TestColorFGBGBackground(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   Background
+		wantOK bool
+	}{
+		{"15;0", BackgroundDark, true},
+		{"0;15", BackgroundLight, true},
+		{"0;default;7", BackgroundLight, true},
+		{"7;8", BackgroundDark, true},
+		{"15;default", BackgroundDark, false},
+		{"", BackgroundDark, false},
+	}
+	for _, tt := range tests {
+		got, ok := colorFGBGBackground(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("colorFGBGBackground(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveBackgroundOverrides(t *testing.T) {
+	t.Setenv(BackgroundEnv, "")
+	t.Setenv("COLORFGBG", "0;15")
+
+	if r := ResolveBackground("dark"); r.Background != BackgroundDark || r.Source != "config" {
+		t.Errorf("config should win, got %s", r)
+	}
+	if r := ResolveBackground("auto"); r.Background != BackgroundLight || r.Source != "COLORFGBG" {
+		t.Errorf("expected COLORFGBG light, got %s", r)
+	}
+
+	t.Setenv(BackgroundEnv, "dark")
+	if r := ResolveBackground(""); r.Background != BackgroundDark || r.Source != BackgroundEnv {
+		t.Errorf("env should win over COLORFGBG, got %s", r)
+	}
+}
+
+func TestResolveBackgroundUsesSessionCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(BackgroundEnv, "")
+	t.Setenv("COLORFGBG", "")
+	t.Setenv("TMUX_PANE", "%7")
+
+	saveBackgroundCache(map[string]backgroundEntry{
+		"TMUX_PANE=%7": {Background: "light", Source: "osc11", Luminance: 0.93, CheckedAt: time.Now().Unix()},
+	})
+
+	// 未啟用查詢時不使用 OSC 11 的結果
+	if r := ResolveBackground("auto"); r.Background != BackgroundDark || r.Source != "default" || r.Cached {
+		t.Errorf("expected default dark without query, got %s", r)
+	}
+
+	r := ResolveBackground(BackgroundQuery)
+	if r.Background != BackgroundLight || r.Source != "osc11" || !r.Cached {
+		t.Errorf("expected cached light result, got %s", r)
+	}
+	if got := r.String(); got != "background=light source=osc11 luminance=0.93 (cached)" {
+		t.Errorf("String() = %q", got)
+	}
+}
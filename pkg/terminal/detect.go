@@ -4,8 +4,6 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"syscall"
-	"unsafe"
 )
 
 // RenderMode 終端渲染模式
@@ -49,36 +47,69 @@ func Detect() RenderMode {
 	return ModeASCII
 }
 
-// winsize 是 TIOCGWINSZ ioctl 的回傳結構
-type winsize struct {
-	Row    uint16
-	Col    uint16
-	Xpixel uint16
-	Ypixel uint16
-}
+// HyperlinksEnv 強制開關 OSC 8 超連結的環境變數（"1" 開啟、"0" 關閉）
+const HyperlinksEnv = "CLAUDE_STATUSLINE_HYPERLINKS"
 
-// Width 回傳終端的欄位寬度。
-// 優先用 ioctl TIOCGWINSZ，其次讀 COLUMNS 環境變數，最後預設 120。
-func Width() int {
-	// 嘗試從 stdout/stderr/stdin 取得終端寬度
-	for _, fd := range []uintptr{1, 2, 0} {
-		var ws winsize
-		if _, _, errno := syscall.Syscall(
-			syscall.SYS_IOCTL,
-			fd,
-			syscall.TIOCGWINSZ,
-			uintptr(unsafe.Pointer(&ws)),
-		); errno == 0 && ws.Col > 0 {
-			return int(ws.Col)
-		}
+// hyperlinkPrograms 已知支援 OSC 8 的 TERM_PROGRAM
+var hyperlinkPrograms = []string{"iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio"}
+
+// SupportsHyperlinks 偵測終端是否支援 OSC 8 超連結。
+// 不支援的終端多半會忽略序列，但部分終端（如 Apple Terminal）會把序列印成亂碼，
+// 因此無法確認時回傳 false。
+func SupportsHyperlinks() bool {
+	switch os.Getenv(HyperlinksEnv) {
+	case "1":
+		return true
+	case "0":
+		return false
 	}
 
-	// Fallback: COLUMNS 環境變數
-	if cols := os.Getenv("COLUMNS"); cols != "" {
-		if n, err := strconv.Atoi(cols); err == nil && n > 0 {
-			return n
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	if term == "dumb" || program == "Apple_Terminal" {
+		return false
+	}
+	for _, p := range hyperlinkPrograms {
+		if program == p {
+			return true
 		}
 	}
+	// tmux 3.4 起會轉送 OSC 8
+	if program == "tmux" {
+		return versionAtLeast(os.Getenv("TERM_PROGRAM_VERSION"), 3, 4)
+	}
+	// VTE 0.50 起支援（GNOME Terminal、Tilix 等），VTE_VERSION 為 5000 形式
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	if os.Getenv("KONSOLE_VERSION") != "" || os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" {
+		return true
+	}
+	for _, t := range []string{"xterm-kitty", "alacritty", "foot"} {
+		if strings.HasPrefix(term, t) {
+			return true
+		}
+	}
+	return false
+}
 
-	return 120
+// versionAtLeast 判斷 "major.minor[...]" 形式的版本字串是否不低於指定版本
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	maj, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	if maj != major {
+		return maj > major
+	}
+	if len(parts) < 2 {
+		return minor == 0
+	}
+	// tmux 的次版本可能帶字母（如 3.3a）
+	mi, err := strconv.Atoi(strings.TrimRight(parts[1], "abcdefghijklmnopqrstuvwxyz"))
+	if err != nil {
+		return false
+	}
+	return mi >= minor
 }
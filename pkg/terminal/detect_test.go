@@ -54,3 +54,41 @@ func TestDetectDumbTerminal(t *testing.T) {
 		return
 	}
 }
+
+// clearHyperlinkEnv 清除所有影響超連結偵測的環境變數
+func clearHyperlinkEnv(t *testing.T) {
+	for _, k := range []string{HyperlinksEnv, "TERM", "TERM_PROGRAM", "TERM_PROGRAM_VERSION", "VTE_VERSION", "KONSOLE_VERSION", "WT_SESSION", "KITTY_WINDOW_ID"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestSupportsHyperlinks(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"unknown", map[string]string{"TERM": "xterm-256color"}, false},
+		{"iterm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, true},
+		{"apple terminal", map[string]string{"TERM_PROGRAM": "Apple_Terminal"}, false},
+		{"vte", map[string]string{"VTE_VERSION": "6003"}, true},
+		{"old vte", map[string]string{"VTE_VERSION": "4205"}, false},
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, true},
+		{"windows terminal", map[string]string{"WT_SESSION": "abc"}, true},
+		{"tmux 3.4", map[string]string{"TERM_PROGRAM": "tmux", "TERM_PROGRAM_VERSION": "3.4"}, true},
+		{"tmux 3.3a", map[string]string{"TERM_PROGRAM": "tmux", "TERM_PROGRAM_VERSION": "3.3a"}, false},
+		{"forced on", map[string]string{HyperlinksEnv: "1", "TERM": "dumb"}, true},
+		{"forced off", map[string]string{HyperlinksEnv: "0", "TERM_PROGRAM": "WezTerm"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearHyperlinkEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := SupportsHyperlinks(); got != tt.want {
+				t.Errorf("SupportsHyperlinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
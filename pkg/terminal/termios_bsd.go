@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package terminal
+
+import "syscall"
+
+// 讀寫 termios 的 ioctl 請求碼
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
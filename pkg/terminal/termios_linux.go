@@ -0,0 +1,9 @@
+package terminal
+
+import "syscall"
+
+// 讀寫 termios 的 ioctl 請求碼
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
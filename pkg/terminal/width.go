@@ -0,0 +1,278 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// DefaultWidth 所有來源都無法取得寬度時的預設值
+const DefaultWidth = 120
+
+// WidthEnv 明確指定寬度的環境變數（優先於 COLUMNS 與偵測結果）
+const WidthEnv = "CLAUDE_STATUSLINE_WIDTH"
+
+// maxAncestors 尋找控制終端時最多往上追溯的父行程層數
+const maxAncestors = 5
+
+// commandTimeout 外部指令（tmux、ps）的逾時
+const commandTimeout = 200 * time.Millisecond
+
+// errUnset 來源未設定（非錯誤，只是沒有資料）
+var errUnset = errors.New("unset")
+
+// WidthProbe 單一寬度來源的查詢結果
+type WidthProbe struct {
+	Source string
+	Width  int   // 取得的寬度；失敗時為 0
+	Err    error // 失敗原因
+}
+
+// WidthResolution 寬度解析結果，Probes 依序記錄每個嘗試過的來源（供除錯輸出）
+type WidthResolution struct {
+	Width  int
+	Source string
+	Probes []WidthProbe
+}
+
+// String 說明寬度由哪個來源決定，以及之前的來源為何失敗
+func (r WidthResolution) String() string {
+	var tried []string
+	for _, p := range r.Probes {
+		if p.Err != nil {
+			tried = append(tried, fmt.Sprintf("%s: %v", p.Source, p.Err))
+		}
+	}
+	s := fmt.Sprintf("width=%d source=%s", r.Width, r.Source)
+	if len(tried) > 0 {
+		s += " (skipped " + strings.Join(tried, "; ") + ")"
+	}
+	return s
+}
+
+// widthSource 一個寬度來源
+type widthSource struct {
+	name  string
+	probe func() (int, error)
+}
+
+// ResolveWidth 依序嘗試各寬度來源，回傳第一個成功的結果：
+// 設定檔 → CLAUDE_STATUSLINE_WIDTH → COLUMNS → stderr/stdout/stdin ioctl → /dev/tty →
+// tmux pane（TMUX_PANE）→ 父行程的控制終端 → DefaultWidth。
+// Claude Code 以 pipe 連接 statusline 的 stdin/stdout，因此常需要後面幾個來源。
+func ResolveWidth(configured int) WidthResolution {
+	sources := []widthSource{
+		{"config", func() (int, error) {
+			if configured <= 0 {
+				return 0, errUnset
+			}
+			return configured, nil
+		}},
+		{WidthEnv, func() (int, error) { return envWidth(WidthEnv) }},
+		{"COLUMNS", func() (int, error) { return envWidth("COLUMNS") }},
+		{"ioctl stderr", func() (int, error) { return fdWidth(2) }},
+		{"ioctl stdout", func() (int, error) { return fdWidth(1) }},
+		{"ioctl stdin", func() (int, error) { return fdWidth(0) }},
+		{"/dev/tty", func() (int, error) { return pathWidth("/dev/tty") }},
+		{"tmux", tmuxWidth},
+		{"parent tty", parentTTYWidth},
+	}
+
+	var r WidthResolution
+	for _, src := range sources {
+		w, err := src.probe()
+		if err == nil && w <= 0 {
+			err = fmt.Errorf("invalid width %d", w)
+		}
+		r.Probes = append(r.Probes, WidthProbe{Source: src.name, Width: w, Err: err})
+		if err == nil {
+			r.Width, r.Source = w, src.name
+			return r
+		}
+	}
+	r.Width, r.Source = DefaultWidth, "default"
+	return r
+}
+
+// Width 回傳終端的欄位寬度（不含設定檔來源的 ResolveWidth）
+func Width() int {
+	return ResolveWidth(0).Width
+}
+
+// envWidth 讀取環境變數中的寬度
+func envWidth(name string) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, errUnset
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", v)
+	}
+	return n, nil
+}
+
+// winsize 是 TIOCGWINSZ ioctl 的回傳結構
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+// fdWidth 以 ioctl TIOCGWINSZ 查詢檔案描述子所連接終端的寬度
+func fdWidth(fd uintptr) (int, error) {
+	var ws winsize
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		fd,
+		syscall.TIOCGWINSZ,
+		uintptr(unsafe.Pointer(&ws)),
+	); errno != 0 {
+		return 0, errno
+	}
+	return int(ws.Col), nil
+}
+
+// pathWidth 開啟終端裝置並查詢寬度
+func pathWidth(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return fdWidth(f.Fd())
+}
+
+// tmuxWidth 在 tmux 中以 TMUX_PANE 查詢 pane 寬度
+func tmuxWidth() (int, error) {
+	pane := os.Getenv("TMUX_PANE")
+	if pane == "" || os.Getenv("TMUX") == "" {
+		return 0, errUnset
+	}
+	out, err := runCommand("tmux", "display-message", "-p", "-t", pane, "#{pane_width}")
+	if err != nil {
+		return 0, err
+	}
+	return parseWidth(out)
+}
+
+// parentTTYWidth 查詢父行程控制終端的寬度
+func parentTTYWidth() (int, error) {
+	tty, err := parentTTY()
+	if err != nil {
+		return 0, err
+	}
+	return pathWidth(tty)
+}
+
+// parentTTY 往上追溯父行程，回傳第一個有控制終端的行程所用的終端裝置路徑
+func parentTTY() (string, error) {
+	pid := os.Getppid()
+	for i := 0; i < maxAncestors && pid > 1; i++ {
+		ppid, tty, err := processInfo(pid)
+		if err != nil {
+			return "", err
+		}
+		if tty != "" {
+			return tty, nil
+		}
+		pid = ppid
+	}
+	return "", errors.New("no controlling terminal")
+}
+
+// processInfo 取得行程的父行程 ID 與控制終端裝置路徑（沒有時為空字串）。
+// Linux 讀取 /proc，其他平台退回 ps。
+func processInfo(pid int) (ppid int, tty string, err error) {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		return parseProcStat(string(data))
+	}
+
+	out, err := runCommand("ps", "-o", "ppid=,tty=", "-p", strconv.Itoa(pid))
+	if err != nil {
+		return 0, "", err
+	}
+	return parsePS(out)
+}
+
+// parseProcStat 解析 /proc/<pid>/stat 的 ppid（第 4 欄）與 tty_nr（第 7 欄）。
+// comm 欄位可能含空白與括號，因此從最後一個 ")" 之後開始切欄位。
+func parseProcStat(stat string) (ppid int, tty string, err error) {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return 0, "", errors.New("malformed /proc stat")
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 5 {
+		return 0, "", errors.New("malformed /proc stat")
+	}
+	ppid, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", err
+	}
+	ttyNr, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return 0, "", err
+	}
+	return ppid, ttyPath(ttyNr), nil
+}
+
+// ttyPath 將 tty_nr 裝置編號轉為裝置路徑；只處理 pseudo terminal 與 virtual console
+func ttyPath(ttyNr int) string {
+	if ttyNr == 0 {
+		return ""
+	}
+	major := (ttyNr >> 8) & 0xfff
+	minor := (ttyNr & 0xff) | ((ttyNr >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("/dev/pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("/dev/tty%d", minor)
+	}
+	return ""
+}
+
+// parsePS 解析 `ps -o ppid=,tty=` 的輸出（如 "  1234 ttys003"、"1 ??"）
+func parsePS(out string) (ppid int, tty string, err error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, "", errors.New("process not found")
+	}
+	ppid, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", err
+	}
+	if len(fields) > 1 && fields[1] != "?" && fields[1] != "??" && fields[1] != "-" {
+		tty = fields[1]
+		if !strings.HasPrefix(tty, "/dev/") {
+			tty = "/dev/" + tty
+		}
+	}
+	return ppid, tty, nil
+}
+
+// parseWidth 解析指令輸出的寬度數字
+func parseWidth(out string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("invalid width %q", strings.TrimSpace(out))
+	}
+	return n, nil
+}
+
+// runCommand 執行外部指令並回傳 stdout，逾時 commandTimeout
+func runCommand(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return string(out), err
+}
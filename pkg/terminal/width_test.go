@@ -1,6 +1,7 @@
 package terminal
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -30,3 +31,74 @@ func TestWidthInvalidColumns(t *testing.T) {
 		t.Errorf("Width() with invalid COLUMNS = %d, want > 0", w)
 	}
 }
+
+func TestResolveWidthOrder(t *testing.T) {
+	t.Setenv(WidthEnv, "90")
+	t.Setenv("COLUMNS", "80")
+
+	if r := ResolveWidth(100); r.Width != 100 || r.Source != "config" {
+		t.Errorf("expected config width to win, got %s", r)
+	}
+	if r := ResolveWidth(0); r.Width != 90 || r.Source != WidthEnv {
+		t.Errorf("expected %s to win, got %s", WidthEnv, r)
+	}
+
+	t.Setenv(WidthEnv, "")
+	r := ResolveWidth(0)
+	if r.Width != 80 || r.Source != "COLUMNS" {
+		t.Errorf("expected COLUMNS to win, got %s", r)
+	}
+	// 除錯輸出列出被略過的來源
+	if s := r.String(); !strings.Contains(s, "source=COLUMNS") || !strings.Contains(s, "config: unset") {
+		t.Errorf("unexpected debug string: %q", s)
+	}
+
+	t.Setenv("COLUMNS", "wide")
+	if r := ResolveWidth(0); r.Source == "COLUMNS" || r.Width <= 0 {
+		t.Errorf("expected invalid COLUMNS to be skipped, got %s", r)
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	// comm 含空白與括號；tty_nr 34817 = major 136, minor 1
+	stat := "4242 (my (odd) proc) S 4200 4242 4242 34817 4242 4194304 0 0"
+	ppid, tty, err := parseProcStat(stat)
+	if err != nil || ppid != 4200 || tty != "/dev/pts/1" {
+		t.Errorf("parseProcStat = %d, %q, %v", ppid, tty, err)
+	}
+
+	if _, tty, _ := parseProcStat("1 (init) S 0 1 1 0 -1 0"); tty != "" {
+		t.Errorf("expected no tty for tty_nr 0, got %q", tty)
+	}
+	if _, _, err := parseProcStat("garbage"); err == nil {
+		t.Error("expected error for malformed stat")
+	}
+}
+
+func TestTTYPath(t *testing.T) {
+	cases := map[int]string{
+		0:              "",
+		136<<8 | 5:     "/dev/pts/5",
+		137<<8 | 2:     "/dev/pts/258",
+		136<<8 | 1<<20: "/dev/pts/256", // minor 高位元
+		4<<8 | 2:       "/dev/tty2",
+		5<<8 | 1:       "",
+	}
+	for nr, want := range cases {
+		if got := ttyPath(nr); got != want {
+			t.Errorf("ttyPath(%d) = %q, want %q", nr, got, want)
+		}
+	}
+}
+
+func TestParsePS(t *testing.T) {
+	if ppid, tty, err := parsePS("  1234 ttys003\n"); err != nil || ppid != 1234 || tty != "/dev/ttys003" {
+		t.Errorf("parsePS = %d, %q, %v", ppid, tty, err)
+	}
+	if ppid, tty, err := parsePS("1 ??"); err != nil || ppid != 1 || tty != "" {
+		t.Errorf("expected no tty for ??, got %d, %q, %v", ppid, tty, err)
+	}
+	if _, _, err := parsePS(""); err == nil {
+		t.Error("expected error for empty output")
+	}
+}
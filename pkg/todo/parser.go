@@ -4,122 +4,164 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+	"github.com/howie/claude-code-omystatusline/pkg/terminal"
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
+// RenderMode 控制進度條的渲染模式（由 main 設定）
+var RenderMode = terminal.ModeTrueColor
+
+// Todo 狀態
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// DefaultMaxRows 完整清單模式預設最多顯示的項目數
+const DefaultMaxRows = 5
+
+// TodoItem 清單中的單一項目
+type TodoItem struct {
+	Content     string
+	Status      string
+	DurationSec int // 已完成項目從 in_progress 到 completed 的秒數；未知時為 0
+	ElapsedSec  int // 進行中項目已進行的秒數；未知時為 0
+}
+
 // TodoInfo 代表 Todo 追蹤狀態
 type TodoInfo struct {
-	InProgressName string // 目前進行中的任務名稱
-	Completed      int    // 已完成數量
-	Total          int    // 總數
-	AllComplete    bool   // 是否全部完成
+	InProgressName string     // 目前進行中的任務名稱
+	Completed      int        // 已完成數量
+	Total          int        // 總數
+	AllComplete    bool       // 是否全部完成
+	Items          []TodoItem // 完整清單（依建立順序）
+	ElapsedSec     int        // 目前項目進入 in_progress 後經過的秒數；無法得知時為 0
+	AvgTaskSec     int        // 已完成項目的平均耗時（只計入有經過 in_progress 的項目）；無資料時為 0
+	ETASec         int        // 剩餘項目的預估時間；無平均耗時可用時為 0
 }
 
-// Analyze 從 transcript 行中找出最新的 TodoWrite 狀態
+// Analyze 依序重播 transcript 行中的任務工具呼叫，回傳目前的任務狀態；沒有任何任務工具呼叫時回傳 nil
 func Analyze(lines []transcript.Line) *TodoInfo {
-	// 從後往前找最新的 TodoWrite tool_use
-	for i := len(lines) - 1; i >= 0; i-- {
-		l := lines[i]
-		if l.Parsed == nil {
-			continue
-		}
+	state := NewState("")
+	state.Apply(lines)
+	return state.Info()
+}
 
-		msg, ok := l.Parsed["message"].(map[string]interface{})
-		if !ok {
-			continue
-		}
+// Format 格式化 Todo 資訊為顯示字串
+func Format(info *TodoInfo) string {
+	if info == nil || info.Total == 0 {
+		return ""
+	}
 
-		role, _ := msg["role"].(string)
-		if role != "assistant" {
-			continue
+	if info.AllComplete {
+		done := fmt.Sprintf("✓ All complete (%d/%d)", info.Completed, info.Total)
+		if info.AvgTaskSec > 0 {
+			done += " avg " + formatElapsed(info.AvgTaskSec)
 		}
+		return done
+	}
 
-		content, ok := msg["content"].([]interface{})
-		if !ok {
-			continue
-		}
+	var parts []string
 
-		for _, block := range content {
-			blockMap, ok := block.(map[string]interface{})
-			if !ok {
-				continue
-			}
+	if info.InProgressName != "" {
+		parts = append(parts, fmt.Sprintf("▸ %s", info.InProgressName))
+	}
 
-			blockType, _ := blockMap["type"].(string)
-			name, _ := blockMap["name"].(string)
+	parts = append(parts, fmt.Sprintf("(%d/%d)", info.Completed, info.Total))
 
-			if blockType == "tool_use" && name == "TodoWrite" {
-				return parseTodoInput(blockMap)
-			}
-		}
+	if info.ETASec > 0 {
+		parts = append(parts, "ETA ~"+formatElapsed(info.ETASec))
 	}
 
-	return nil
+	return strings.Join(parts, " ")
 }
 
-func parseTodoInput(block map[string]interface{}) *TodoInfo {
-	input, ok := block["input"].(map[string]interface{})
-	if !ok {
+// FormatFull 格式化完整清單（expanded 模式）：第一行為進度條、完成數與平均耗時/ETA，
+// 之後每個項目一行（✓ 完成附耗時、▸ 進行中附已進行時間、○ 待辦），最多 maxRows 個項目，其餘以 "+N more" 表示
+func FormatFull(info *TodoInfo, maxRows int) []string {
+	if info == nil || info.Total == 0 {
 		return nil
 	}
-
-	todos, ok := input["todos"].([]interface{})
-	if !ok {
-		return nil
+	if info.AllComplete {
+		return []string{Format(info)}
+	}
+	if maxRows <= 0 {
+		maxRows = DefaultMaxRows
 	}
 
-	info := &TodoInfo{Total: len(todos)}
-	var inProgressName string
-
-	for _, item := range todos {
-		itemMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		status, _ := itemMap["status"].(string)
-		content, _ := itemMap["content"].(string)
-
-		switch status {
-		case "completed":
-			info.Completed++
-		case "in_progress":
-			if inProgressName == "" {
-				inProgressName = content
-			}
+	header := fmt.Sprintf("%s %d/%d", progressBar(info.Completed, info.Total), info.Completed, info.Total)
+	if info.AvgTaskSec > 0 {
+		header += fmt.Sprintf(" · avg %s · ETA ~%s", formatElapsed(info.AvgTaskSec), formatElapsed(info.ETASec))
+	}
+	rows := []string{header}
+
+	start, end := rowWindow(info.Items, maxRows)
+	for _, item := range info.Items[start:end] {
+		row := fmt.Sprintf("%s %s", statusIcon(item.Status), statusline.Truncate(item.Content, 50, "..."))
+		switch {
+		case item.Status == StatusInProgress && item.ElapsedSec > 0:
+			row += " " + formatElapsed(item.ElapsedSec)
+		case item.Status == StatusCompleted && item.DurationSec > 0:
+			row += " " + formatElapsed(item.DurationSec)
 		}
+		rows = append(rows, row)
 	}
-
-	info.InProgressName = truncateContent(inProgressName, 50)
-	info.AllComplete = info.Completed == info.Total && info.Total > 0
-
-	return info
+	if hidden := len(info.Items) - end; hidden > 0 {
+		rows = append(rows, fmt.Sprintf("+%d more", hidden))
+	}
+	return rows
 }
 
-func truncateContent(content string, maxLen int) string {
-	if len(content) <= maxLen {
-		return content
+// rowWindow 選出要顯示的項目範圍：從第一個未完成項目的前一項開始，
+// 讓最近完成的一項保留作為上下文，其餘已完成項目由進度條代表
+func rowWindow(items []TodoItem, maxRows int) (start, end int) {
+	if len(items) <= maxRows {
+		return 0, len(items)
+	}
+	for start < len(items) && items[start].Status == StatusCompleted {
+		start++
+	}
+	if start > 0 {
+		start--
+	}
+	if start > len(items)-maxRows {
+		start = len(items) - maxRows
 	}
-	return content[:maxLen-3] + "..."
+	return start, start + maxRows
 }
 
-// Format 格式化 Todo 資訊為顯示字串
-func Format(info *TodoInfo) string {
-	if info == nil || info.Total == 0 {
-		return ""
+func statusIcon(status string) string {
+	switch status {
+	case StatusCompleted:
+		return "✓"
+	case StatusInProgress:
+		return "▸"
+	default:
+		return "○"
 	}
+}
 
-	if info.AllComplete {
-		return fmt.Sprintf("✓ All complete (%d/%d)", info.Completed, info.Total)
+// progressBar 產生 5 格的迷你進度條
+func progressBar(completed, total int) string {
+	const width = 5
+	filled := 0
+	if total > 0 {
+		filled = completed * width / total
 	}
-
-	var parts []string
-
-	if info.InProgressName != "" {
-		parts = append(parts, fmt.Sprintf("▸ %s", info.InProgressName))
+	if RenderMode == terminal.ModeASCII {
+		return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
 	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
 
-	parts = append(parts, fmt.Sprintf("(%d/%d)", info.Completed, info.Total))
-
-	return strings.Join(parts, " ")
+func formatElapsed(seconds int) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	if seconds < 3600 {
+		return fmt.Sprintf("%dm%02ds", seconds/60, seconds%60)
+	}
+	return fmt.Sprintf("%dh%02dm", seconds/3600, (seconds%3600)/60)
 }
@@ -3,6 +3,7 @@ package todo
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
@@ -122,3 +123,86 @@ func TestFormat(t *testing.T) {
 		return
 	}
 }
+
+// todoWrite 產生帶時間戳的 TodoWrite 行；items 為 content, status 交錯
+func todoWrite(ts string, items ...string) transcript.Line {
+	var todos []interface{}
+	for i := 0; i+1 < len(items); i += 2 {
+		todos = append(todos, map[string]interface{}{"content": items[i], "status": items[i+1]})
+	}
+	return transcript.Line{Parsed: map[string]interface{}{
+		"timestamp": ts,
+		"message": map[string]interface{}{
+			"role": "assistant",
+			"content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "name": "TodoWrite", "input": map[string]interface{}{"todos": todos}},
+			},
+		},
+	}}
+}
+
+func TestAnalyzeInProgressElapsed(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+
+	lines := []transcript.Line{
+		todoWrite(ts(20*time.Minute), "A", "in_progress", "B", "pending"),
+		todoWrite(ts(10*time.Minute), "A", "completed", "B", "in_progress"),
+		// B 仍在進行中：起算時間維持在第一次標為 in_progress 的時候
+		todoWrite(ts(2*time.Minute), "A", "completed", "B", "in_progress", "C", "pending"),
+	}
+
+	info := Analyze(lines)
+	if info == nil || len(info.Items) != 3 {
+		t.Fatalf("expected 3 items, got %+v", info)
+	}
+	if info.ElapsedSec < 599 || info.ElapsedSec > 605 {
+		t.Errorf("expected ~600s elapsed since B started, got %d", info.ElapsedSec)
+	}
+}
+
+func TestFormatFull(t *testing.T) {
+	info := &TodoInfo{
+		Completed:  3,
+		Total:      8,
+		ElapsedSec: 192,
+		Items: []TodoItem{
+			{Content: "A", Status: StatusCompleted}, {Content: "B", Status: StatusCompleted},
+			{Content: "C", Status: StatusCompleted}, {Content: "D", Status: StatusInProgress, ElapsedSec: 192},
+			{Content: "E", Status: StatusPending}, {Content: "F", Status: StatusPending},
+			{Content: "G", Status: StatusPending}, {Content: "H", Status: StatusPending},
+		},
+	}
+
+	rows := FormatFull(info, 4)
+	want := []string{"█░░░░ 3/8", "✓ C", "▸ D 3m12s", "○ E", "○ F", "+2 more"}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FormatFull = %q, want %q", rows, want)
+	}
+
+	// 未超過上限時全部顯示
+	if rows := FormatFull(info, 10); len(rows) != 9 || rows[1] != "✓ A" {
+		t.Errorf("expected header + 8 items, got %q", rows)
+	}
+
+	// 全部完成時只顯示摘要
+	done := &TodoInfo{Completed: 2, Total: 2, AllComplete: true}
+	if rows := FormatFull(done, 5); len(rows) != 1 || !strings.Contains(rows[0], "All complete") {
+		t.Errorf("unexpected all-complete rows: %q", rows)
+	}
+}
+
+func TestFormatFullTruncatesCJKByWidth(t *testing.T) {
+	info := &TodoInfo{
+		Total: 1,
+		Items: []TodoItem{{Content: strings.Repeat("修正登入流程", 5), Status: StatusPending}},
+	}
+	rows := FormatFull(info, 5)
+	if len(rows) != 2 {
+		t.Fatalf("expected header + 1 item, got %q", rows)
+	}
+	// 中文每字 2 欄：50 欄扣掉 "..." 後保留 23 字
+	if want := "○ " + strings.Repeat("修正登入流程", 3) + "修正登入流" + "..."; rows[1] != want {
+		t.Errorf("row = %q, want %q", rows[1], want)
+	}
+}
@@ -0,0 +1,428 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// ToolNames 各種任務工具的名稱。
+// Snapshot 工具每次呼叫帶完整清單（TodoWrite）；Create/Update 工具以任務 ID 逐筆增修（TaskCreate/TaskUpdate）。
+type ToolNames struct {
+	Snapshot []string
+	Create   []string
+	Update   []string
+}
+
+// Tools 目前辨識的任務工具名稱（main 依設定覆寫，之後僅讀取）
+var Tools = ToolNames{
+	Snapshot: []string{"TodoWrite"},
+	Create:   []string{"TaskCreate"},
+	Update:   []string{"TaskUpdate"},
+}
+
+// statusDeleted 增量工具以此狀態刪除任務
+const statusDeleted = "deleted"
+
+// task 重播後的單一任務
+type task struct {
+	ID          string `json:"id,omitempty"` // 增量工具的任務 ID；快照工具建立的項目為空
+	Content     string `json:"content"`
+	Status      string `json:"status"`
+	StartedMs   int64  `json:"started_ms,omitempty"`   // 進入 in_progress 的時間；0 代表未知
+	CompletedMs int64  `json:"completed_ms,omitempty"` // 進入 completed 的時間
+	DurationMs  int64  `json:"duration_ms,omitempty"`  // in_progress 到 completed 的耗時；沒經過 in_progress 時為 0
+}
+
+// transition 變更任務狀態並記錄 pending → in_progress → completed 的時間點
+func (t *task) transition(status string, nowMs int64) {
+	if status == t.Status {
+		return
+	}
+	switch status {
+	case StatusInProgress:
+		t.StartedMs = nowMs
+		t.CompletedMs, t.DurationMs = 0, 0
+	case StatusCompleted:
+		t.CompletedMs = nowMs
+		if t.StartedMs > 0 && nowMs >= t.StartedMs {
+			t.DurationMs = nowMs - t.StartedMs
+		}
+	default:
+		t.StartedMs, t.CompletedMs, t.DurationMs = 0, 0, 0
+	}
+	t.Status = status
+}
+
+// State 依序重播任務工具呼叫得到的任務清單，連同增量掃描的進度一起快取
+type State struct {
+	Path    string            `json:"path"`   // 來源 transcript 路徑
+	Offset  int64             `json:"offset"` // 已掃描到的 byte 位移
+	Seen    bool              `json:"seen"`   // 是否出現過任何任務工具呼叫
+	Tasks   []*task           `json:"tasks"`
+	NextID  int               `json:"next_id"` // 預估的下一個任務 ID（實際 ID 以 tool_result 為準）
+	Pending map[string]string `json:"pending"` // create 的 toolUseId -> 暫定任務 ID，等待 tool_result 回報實際 ID
+}
+
+// NewState 建立空的任務狀態
+func NewState(path string) *State {
+	return &State{Path: path, NextID: 1, Pending: make(map[string]string)}
+}
+
+// taskIDPattern 從 create 的 tool_result 文字中取出任務 ID（如 "Task #3 created successfully"）
+var taskIDPattern = regexp.MustCompile(`Task #(\w+)`)
+
+// Apply 依序套用 transcript 行中的任務工具呼叫與對應結果
+func (s *State) Apply(lines []transcript.Line) {
+	for _, l := range lines {
+		if l.Parsed == nil {
+			continue
+		}
+		msg, ok := l.Parsed["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].([]interface{})
+		nowMs := l.UnixMilli()
+
+		for _, block := range content {
+			blockMap, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			blockType, _ := blockMap["type"].(string)
+
+			if role == "assistant" && blockType == "tool_use" {
+				name, _ := blockMap["name"].(string)
+				input, _ := blockMap["input"].(map[string]interface{})
+				toolID, _ := blockMap["id"].(string)
+				switch {
+				case contains(Tools.Snapshot, name):
+					s.applySnapshot(input, nowMs)
+				case contains(Tools.Create, name):
+					s.applyCreate(toolID, input, nowMs)
+				case contains(Tools.Update, name):
+					s.applyUpdate(input, nowMs)
+				}
+			}
+
+			if role == "user" && blockType == "tool_result" {
+				toolID, _ := blockMap["tool_use_id"].(string)
+				if provisional, ok := s.Pending[toolID]; ok {
+					delete(s.Pending, toolID)
+					s.resolveCreate(provisional, l.Parsed, blockMap)
+				}
+			}
+		}
+	}
+}
+
+// applySnapshot 以完整清單取代目前狀態；以內容對應前一次的項目，延續各項目的狀態轉換時間
+func (s *State) applySnapshot(input map[string]interface{}, nowMs int64) {
+	todos, ok := input["todos"].([]interface{})
+	if !ok {
+		return
+	}
+	s.Seen = true
+
+	prev := make(map[string]*task, len(s.Tasks))
+	for _, t := range s.Tasks {
+		prev[t.Content] = t
+	}
+
+	tasks := make([]*task, 0, len(todos))
+	for _, item := range todos {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, _ := itemMap["content"].(string)
+		status, _ := itemMap["status"].(string)
+		t := &task{Content: content}
+		if p, ok := prev[content]; ok {
+			*t = *p
+		}
+		t.transition(status, nowMs)
+		tasks = append(tasks, t)
+	}
+	s.Tasks = tasks
+}
+
+// applyCreate 新增一筆任務，先給暫定 ID，收到 tool_result 後再修正
+func (s *State) applyCreate(toolID string, input map[string]interface{}, nowMs int64) {
+	if input == nil {
+		return
+	}
+	s.Seen = true
+
+	t := &task{ID: strconv.Itoa(s.NextID), Content: firstString(input, "subject", "content", "description")}
+	status, _ := input["status"].(string)
+	if status == "" {
+		status = StatusPending
+	}
+	t.transition(status, nowMs)
+	s.NextID++
+	s.Tasks = append(s.Tasks, t)
+	if toolID != "" {
+		s.Pending[toolID] = t.ID
+	}
+}
+
+// resolveCreate 依 create 的結果修正任務 ID；失敗的 create 移除暫定任務
+func (s *State) resolveCreate(provisional string, parsed, result map[string]interface{}) {
+	idx := s.find(provisional)
+	if idx < 0 {
+		return
+	}
+	if isErr, _ := result["is_error"].(bool); isErr {
+		s.Tasks = append(s.Tasks[:idx], s.Tasks[idx+1:]...)
+		// 失敗的 create 不佔用 ID
+		if provisional == strconv.Itoa(s.NextID-1) {
+			s.NextID--
+		}
+		return
+	}
+
+	id := ""
+	if r, ok := parsed["toolUseResult"].(map[string]interface{}); ok {
+		if t, ok := r["task"].(map[string]interface{}); ok {
+			id = idString(t["id"])
+		}
+	}
+	if id == "" {
+		if m := taskIDPattern.FindStringSubmatch(resultText(result)); m != nil {
+			id = m[1]
+		}
+	}
+	if id == "" || id == provisional {
+		return
+	}
+	s.Tasks[idx].ID = id
+	if n, err := strconv.Atoi(id); err == nil && n >= s.NextID {
+		s.NextID = n + 1
+	}
+}
+
+// applyUpdate 依任務 ID 更新狀態或內容；狀態為 deleted 時移除
+func (s *State) applyUpdate(input map[string]interface{}, nowMs int64) {
+	idx := s.find(idString(input["taskId"]))
+	if idx < 0 {
+		return
+	}
+	s.Seen = true
+
+	t := s.Tasks[idx]
+	if subject := firstString(input, "subject", "content"); subject != "" {
+		t.Content = subject
+	}
+	status, _ := input["status"].(string)
+	switch {
+	case status == statusDeleted:
+		s.Tasks = append(s.Tasks[:idx], s.Tasks[idx+1:]...)
+	case status != "":
+		t.transition(status, nowMs)
+	}
+}
+
+func (s *State) find(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, t := range s.Tasks {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Info 將目前狀態轉為顯示用的 TodoInfo；沒有任何任務工具呼叫時回傳 nil
+func (s *State) Info() *TodoInfo {
+	if s == nil || !s.Seen {
+		return nil
+	}
+
+	info := &TodoInfo{Total: len(s.Tasks)}
+	var totalMs int64
+	var timed, pending int
+	var running []int // 進行中項目的已進行秒數
+	for _, t := range s.Tasks {
+		item := TodoItem{Content: t.Content, Status: t.Status, DurationSec: int(t.DurationMs / 1000)}
+		switch t.Status {
+		case StatusCompleted:
+			info.Completed++
+			if t.DurationMs > 0 {
+				totalMs += t.DurationMs
+				timed++
+			}
+		case StatusInProgress:
+			if t.StartedMs > 0 {
+				item.ElapsedSec = int(time.Since(time.UnixMilli(t.StartedMs)).Seconds())
+				if item.ElapsedSec < 0 {
+					item.ElapsedSec = 0
+				}
+			}
+			running = append(running, item.ElapsedSec)
+			if info.InProgressName == "" {
+				info.InProgressName = statusline.Truncate(t.Content, 50, "...")
+				info.ElapsedSec = item.ElapsedSec
+			}
+		default:
+			pending++
+		}
+		info.Items = append(info.Items, item)
+	}
+	info.AllComplete = info.Completed == info.Total && info.Total > 0
+
+	// ETA：待辦項目各以平均耗時估計，進行中項目扣除已進行的時間
+	if timed > 0 {
+		info.AvgTaskSec = int(totalMs / int64(timed) / 1000)
+		eta := pending * info.AvgTaskSec
+		for _, elapsed := range running {
+			if left := info.AvgTaskSec - elapsed; left > 0 {
+				eta += left
+			}
+		}
+		info.ETASec = eta
+	}
+	return info
+}
+
+// SessionTodos 增量重播整份 transcript 的任務工具呼叫，回傳目前的任務狀態。
+// 增量工具的任務可能在很久之前建立，因此不能只看 transcript 尾端；進度快取在 cache 目錄。
+func SessionTodos(transcriptPath, sessionID string) *TodoInfo {
+	if transcriptPath == "" {
+		return nil
+	}
+
+	state := loadState(sessionID)
+	if state == nil || state.Path != transcriptPath {
+		state = NewState(transcriptPath)
+	}
+
+	lines, offset, err := transcript.ReadFrom(transcriptPath, state.Offset)
+	if errors.Is(err, transcript.ErrTruncated) {
+		state = NewState(transcriptPath)
+		lines, offset, err = transcript.ReadFrom(transcriptPath, 0)
+	}
+	if err != nil && len(lines) == 0 {
+		return nil
+	}
+
+	state.Apply(lines)
+	state.Offset = offset
+	saveState(sessionID, state)
+	return state.Info()
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// firstString 取第一個非空的字串欄位
+func firstString(input map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := input[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// idString 將字串或數字型別的任務 ID 統一為字串
+func idString(v interface{}) string {
+	switch id := v.(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatInt(int64(id), 10)
+	}
+	return ""
+}
+
+// resultText 取出 tool_result 的文字內容（字串或 text 區塊陣列）
+func resultText(result map[string]interface{}) string {
+	switch c := result["content"].(type) {
+	case string:
+		return c
+	case []interface{}:
+		var parts []string
+		for _, item := range c {
+			if m, ok := item.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+func getStateCachePath(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", fmt.Sprintf("todos-%s.json", sessionID))
+}
+
+func loadState(sessionID string) *State {
+	path := getStateCachePath(sessionID)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	if state.Pending == nil {
+		state.Pending = make(map[string]string)
+	}
+	if state.NextID < 1 {
+		state.NextID = 1
+	}
+	return &state
+}
+
+func saveState(sessionID string, state *State) {
+	path := getStateCachePath(sessionID)
+	if path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, 0644)
+}
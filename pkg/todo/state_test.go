@@ -0,0 +1,180 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// taskCall 產生任務工具的 tool_use 行
+func taskCall(toolID, name string, input map[string]interface{}) transcript.Line {
+	return transcript.Line{Parsed: map[string]interface{}{
+		"timestamp": "2026-01-01T00:00:00Z",
+		"message": map[string]interface{}{
+			"role": "assistant",
+			"content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": toolID, "name": name, "input": input},
+			},
+		},
+	}}
+}
+
+// taskResult 產生 create 的 tool_result 行；taskID 為空時不帶 toolUseResult
+func taskResult(toolID, text, taskID string, isErr bool) transcript.Line {
+	parsed := map[string]interface{}{
+		"message": map[string]interface{}{
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": toolID, "content": text, "is_error": isErr},
+			},
+		},
+	}
+	if taskID != "" {
+		parsed["toolUseResult"] = map[string]interface{}{"task": map[string]interface{}{"id": taskID}}
+	}
+	return transcript.Line{Parsed: parsed}
+}
+
+func TestAnalyzeIncrementalTasks(t *testing.T) {
+	lines := []transcript.Line{
+		taskCall("t1", "TaskCreate", map[string]interface{}{"subject": "Write parser"}),
+		taskResult("t1", "Task #4 created successfully: Write parser", "", false),
+		taskCall("t2", "TaskCreate", map[string]interface{}{"subject": "Add tests"}),
+		taskResult("t2", "created", "5", false),
+		taskCall("t3", "TaskCreate", map[string]interface{}{"subject": "Broken"}),
+		taskResult("t3", "error", "", true),
+		taskCall("t4", "TaskCreate", map[string]interface{}{"subject": "Update docs"}),
+		taskCall("u1", "TaskUpdate", map[string]interface{}{"taskId": "4", "status": "completed"}),
+		taskCall("u2", "TaskUpdate", map[string]interface{}{"taskId": float64(5), "status": "in_progress"}),
+		// 沒有 tool_result 的 create 以預估 ID 追蹤
+		taskCall("u3", "TaskUpdate", map[string]interface{}{"taskId": "6", "status": "deleted"}),
+		taskCall("l1", "TaskList", map[string]interface{}{}),
+	}
+
+	info := Analyze(lines)
+	if info == nil {
+		t.Fatal("expected TodoInfo")
+	}
+	if info.Total != 2 || info.Completed != 1 || info.InProgressName != "Add tests" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := Format(info); got != "▸ Add tests (1/2)" {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
+
+func TestAnalyzeSnapshotReplacesList(t *testing.T) {
+	lines := []transcript.Line{
+		taskCall("t1", "TaskCreate", map[string]interface{}{"subject": "Old"}),
+		todoWrite("2026-01-01T00:00:00Z", "A", "completed", "B", "pending"),
+	}
+	info := Analyze(lines)
+	if info == nil || info.Total != 2 || info.Items[0].Content != "A" {
+		t.Errorf("expected snapshot to replace the list, got %+v", info)
+	}
+}
+
+func TestAnalyzeCustomToolNames(t *testing.T) {
+	saved := Tools
+	defer func() { Tools = saved }()
+	Tools.Create = []string{"mcp__tasks__add"}
+	Tools.Update = []string{"mcp__tasks__set"}
+
+	lines := []transcript.Line{
+		taskCall("t1", "mcp__tasks__add", map[string]interface{}{"content": "Custom"}),
+		taskCall("u1", "mcp__tasks__set", map[string]interface{}{"taskId": "1", "status": "in_progress"}),
+	}
+	info := Analyze(lines)
+	if info == nil || info.InProgressName != "Custom" {
+		t.Errorf("expected custom tools to be replayed, got %+v", info)
+	}
+}
+
+func TestSessionTodosIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	write := func(lines ...string) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		for _, l := range lines {
+			if _, err := f.WriteString(l + "\n"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write(`{"message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"TaskCreate","input":{"subject":"First"}}]}}`,
+		`{"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"Task #1 created successfully: First"}]}}`)
+	if info := SessionTodos(path, "s1"); info == nil || info.Total != 1 {
+		t.Fatalf("expected 1 task, got %+v", info)
+	}
+
+	// 第二次只讀取新增的行，先前建立的任務由快取提供
+	write(`{"message":{"role":"assistant","content":[{"type":"tool_use","id":"u1","name":"TaskUpdate","input":{"taskId":"1","status":"completed"}}]}}`)
+	info := SessionTodos(path, "s1")
+	if info == nil || !info.AllComplete || !strings.Contains(Format(info), "All complete (1/1)") {
+		t.Errorf("expected task completed from cached state, got %+v", info)
+	}
+}
+
+func TestTaskDurationsAndETA(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+
+	lines := []transcript.Line{
+		todoWrite(ts(10*time.Minute), "A", "in_progress", "B", "pending", "C", "pending", "D", "pending"),
+		todoWrite(ts(8*time.Minute), "A", "completed", "B", "in_progress", "C", "pending", "D", "pending"),
+		todoWrite(ts(4*time.Minute), "A", "completed", "B", "completed", "C", "pending", "D", "pending"),
+		todoWrite(ts(1*time.Minute), "A", "completed", "B", "completed", "C", "in_progress", "D", "pending"),
+	}
+
+	info := Analyze(lines)
+	if info == nil {
+		t.Fatal("expected TodoInfo")
+	}
+	if info.Items[0].DurationSec != 120 || info.Items[1].DurationSec != 240 {
+		t.Errorf("unexpected durations: %+v", info.Items)
+	}
+	if info.AvgTaskSec != 180 {
+		t.Errorf("expected avg 180s, got %d", info.AvgTaskSec)
+	}
+	// D 需要一個平均耗時，C 已進行約 60 秒
+	if info.ETASec < 295 || info.ETASec > 300 {
+		t.Errorf("expected ETA ~300s, got %d", info.ETASec)
+	}
+	if got := Format(info); !strings.HasPrefix(got, "▸ C (2/4) ETA ~") {
+		t.Errorf("unexpected format: %q", got)
+	}
+	if rows := FormatFull(info, 5); !strings.Contains(rows[0], "avg 3m00s") || rows[1] != "✓ A 2m00s" {
+		t.Errorf("unexpected full rows: %q", rows)
+	}
+}
+
+func TestTaskDurationIncremental(t *testing.T) {
+	at := func(l transcript.Line, ts string) transcript.Line {
+		l.Parsed["timestamp"] = ts
+		return l
+	}
+	lines := []transcript.Line{
+		at(taskCall("t1", "TaskCreate", map[string]interface{}{"subject": "A"}), "2026-01-01T00:00:00Z"),
+		at(taskCall("u1", "TaskUpdate", map[string]interface{}{"taskId": "1", "status": "in_progress"}), "2026-01-01T00:01:00Z"),
+		at(taskCall("u2", "TaskUpdate", map[string]interface{}{"taskId": "1", "subject": "A renamed"}), "2026-01-01T00:02:00Z"),
+		at(taskCall("u3", "TaskUpdate", map[string]interface{}{"taskId": "1", "status": "completed"}), "2026-01-01T00:04:30Z"),
+	}
+
+	info := Analyze(lines)
+	if info == nil || info.Items[0].DurationSec != 210 || info.AvgTaskSec != 210 || !info.AllComplete {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := Format(info); got != "✓ All complete (1/1) avg 3m30s" {
+		t.Errorf("unexpected format: %q", got)
+	}
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+)
+
+// MCPIcon MCP 工具的顯示前綴
+const MCPIcon = "🔌"
+
+// mcpPrefix MCP 工具名稱前綴（mcp__<server>__<tool>）
+const mcpPrefix = "mcp__"
+
+// maxServerLabelLen server 短標籤的最大字元數
+const maxServerLabelLen = 12
+
+// MCPServerUsage 單一 MCP server 在 session 中的呼叫統計
+type MCPServerUsage struct {
+	Server     string `json:"server"`
+	Calls      int    `json:"calls"`
+	Errors     int    `json:"errors"`
+	Configured bool   `json:"configured"` // 是否出現在 settings.json 的 mcpServers
+}
+
+// ParseMCPName 將 "mcp__server__tool" 拆成 server 與 tool；非 MCP 工具回傳 ok=false
+func ParseMCPName(name string) (server, tool string, ok bool) {
+	rest, found := strings.CutPrefix(name, mcpPrefix)
+	if !found {
+		return "", "", false
+	}
+	server, tool, ok = strings.Cut(rest, "__")
+	if !ok || server == "" || tool == "" {
+		return "", "", false
+	}
+	return server, tool, true
+}
+
+// serverLabel 產生 server 的短標籤：plugin 形式（plugin_<plugin>_<server>）只保留最後一段，過長時截斷
+func serverLabel(server string) string {
+	if rest, ok := strings.CutPrefix(server, "plugin_"); ok {
+		if i := strings.LastIndex(rest, "_"); i >= 0 && i < len(rest)-1 {
+			rest = rest[i+1:]
+		}
+		server = rest
+	}
+	return statusline.Truncate(server, maxServerLabelLen, "…")
+}
+
+// DisplayName 回傳工具的顯示名稱：MCP 工具為 "🔌server:tool"，其餘原樣
+func DisplayName(name string) string {
+	server, tool, ok := ParseMCPName(name)
+	if !ok {
+		return name
+	}
+	return MCPIcon + serverLabel(server) + ":" + tool
+}
+
+// MCPUsage 依 server 彙總 MCP 工具的呼叫次數，並與 settings.json 設定的 server 清單對照。
+// 已設定但未使用的 server 也會列出（Calls 為 0）；結果依呼叫次數由多到少排列。
+func MCPUsage(stats *UsageStats, configured []string) []MCPServerUsage {
+	byServer := make(map[string]*MCPServerUsage)
+	for _, server := range configured {
+		byServer[server] = &MCPServerUsage{Server: server, Configured: true}
+	}
+	if stats != nil {
+		for name, st := range stats.Tools {
+			server, _, ok := ParseMCPName(name)
+			if !ok {
+				continue
+			}
+			u, exists := byServer[server]
+			if !exists {
+				u = &MCPServerUsage{Server: server}
+				byServer[server] = u
+			}
+			u.Calls += st.Calls
+			u.Errors += st.Errors
+		}
+	}
+
+	result := make([]MCPServerUsage, 0, len(byServer))
+	for _, u := range byServer {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].Server < result[j].Server
+	})
+	return result
+}
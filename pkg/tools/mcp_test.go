@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMCPName(t *testing.T) {
+	tests := []struct {
+		name         string
+		server, tool string
+		ok           bool
+	}{
+		{"mcp__github__create_issue", "github", "create_issue", true},
+		{"mcp__plugin_linear_linear__list_issues", "plugin_linear_linear", "list_issues", true},
+		{"mcp__broken", "", "", false},
+		{"Bash", "", "", false},
+	}
+	for _, tt := range tests {
+		server, tool, ok := ParseMCPName(tt.name)
+		if server != tt.server || tool != tt.tool || ok != tt.ok {
+			t.Errorf("ParseMCPName(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.name, server, tool, ok, tt.server, tt.tool, tt.ok)
+		}
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	tests := map[string]string{
+		"Read":                                   "Read",
+		"mcp__github__create_issue":              "🔌github:create_issue",
+		"mcp__plugin_linear_linear__list_issues": "🔌linear:list_issues",
+		"mcp__a_very_long_server_name__tool":     "🔌a_very_long…:tool",
+	}
+	for in, want := range tests {
+		if got := DisplayName(in); got != want {
+			t.Errorf("DisplayName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatMCPTool(t *testing.T) {
+	result := Format([]ToolInfo{{Name: "mcp__github__get_pr", Target: "42"}})
+	if !strings.Contains(result, "◐ 🔌github:get_pr: 42") {
+		t.Errorf("expected MCP display name, got %q", result)
+	}
+}
+
+func TestMarkSlowMCPServerThreshold(t *testing.T) {
+	tools := []ToolInfo{
+		{Name: "mcp__github__search", ElapsedSec: 40},
+		{Name: "mcp__slack__post", ElapsedSec: 40},
+	}
+	MarkSlow(tools, map[string]int{"mcp__github": 30, "*": 300})
+	if !tools[0].Slow || tools[1].Slow {
+		t.Errorf("expected only github tool slow, got %+v", tools)
+	}
+}
+
+func TestMCPUsage(t *testing.T) {
+	stats := newUsageStats("t.jsonl")
+	stats.Tools["mcp__github__search"] = &ToolStat{Calls: 3, Errors: 1}
+	stats.Tools["mcp__github__get_pr"] = &ToolStat{Calls: 2}
+	stats.Tools["mcp__local__run"] = &ToolStat{Calls: 1}
+	stats.Tools["Bash"] = &ToolStat{Calls: 9}
+
+	usage := MCPUsage(stats, []string{"github", "slack"})
+	if len(usage) != 3 {
+		t.Fatalf("expected 3 servers, got %+v", usage)
+	}
+	want := []MCPServerUsage{
+		{Server: "github", Calls: 5, Errors: 1, Configured: true},
+		{Server: "local", Calls: 1},
+		{Server: "slack", Configured: true},
+	}
+	for i, w := range want {
+		if usage[i] != w {
+			t.Errorf("usage[%d] = %+v, want %+v", i, usage[i], w)
+		}
+	}
+
+	if got, want := FormatUsage(stats), "Bash×9 🔌github×5(1✗) 🔌local×1"; got != want {
+		t.Errorf("FormatUsage = %q, want %q", got, want)
+	}
+}
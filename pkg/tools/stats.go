@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// ToolStat 單一工具在整個 session 的使用統計
+type ToolStat struct {
+	Calls           int   `json:"calls"`
+	Errors          int   `json:"errors"`            // tool_result 帶 is_error: true 的次數
+	TotalDurationMs int64 `json:"total_duration_ms"` // 有時間戳可計算的呼叫總耗時
+	TimedCalls      int   `json:"timed_calls"`       // 計入 TotalDurationMs 的呼叫數
+}
+
+// AvgDurationMs 平均耗時（毫秒），沒有可計時的呼叫時為 0
+func (s *ToolStat) AvgDurationMs() int64 {
+	if s.TimedCalls == 0 {
+		return 0
+	}
+	return s.TotalDurationMs / int64(s.TimedCalls)
+}
+
+// MarshalJSON 輸出時附上平均耗時
+func (s *ToolStat) MarshalJSON() ([]byte, error) {
+	type plain ToolStat
+	return json.Marshal(struct {
+		*plain
+		AvgDurationMs int64 `json:"avg_duration_ms"`
+	}{(*plain)(s), s.AvgDurationMs()})
+}
+
+// pendingCall 尚未收到 tool_result 的 tool_use
+type pendingCall struct {
+	Name    string `json:"name"`
+	StartMs int64  `json:"start_ms"` // 0 代表 transcript 沒有時間戳
+}
+
+// UsageStats 整個 session 的工具使用統計，連同增量掃描的進度一起快取
+type UsageStats struct {
+	Path    string                  `json:"path"`   // 統計來源的 transcript 路徑
+	Offset  int64                   `json:"offset"` // 已掃描到的 byte 位移
+	Tools   map[string]*ToolStat    `json:"tools"`
+	Pending map[string]*pendingCall `json:"pending"` // toolUseId -> 呼叫資訊，跨次掃描配對用
+}
+
+// MaxUsageTools 使用統計最多顯示的工具數量
+const MaxUsageTools = 6
+
+// maxPending 保留的未配對 tool_use 上限（被中斷的呼叫永遠不會有 tool_result）；
+// 超過時淘汰最早開始的呼叫
+const maxPending = 100
+
+func newUsageStats(path string) *UsageStats {
+	return &UsageStats{
+		Path:    path,
+		Tools:   make(map[string]*ToolStat),
+		Pending: make(map[string]*pendingCall),
+	}
+}
+
+// SessionUsage 增量掃描整份 transcript，回傳 session 的工具使用統計。
+// 掃描進度快取在 cache 目錄，每次只解析上次之後新增的行。
+func SessionUsage(transcriptPath, sessionID string) *UsageStats {
+	if transcriptPath == "" {
+		return nil
+	}
+
+	stats := loadUsage(sessionID)
+	if stats == nil || stats.Path != transcriptPath {
+		stats = newUsageStats(transcriptPath)
+	}
+
+	lines, offset, err := transcript.ReadFrom(transcriptPath, stats.Offset)
+	if errors.Is(err, transcript.ErrTruncated) {
+		stats = newUsageStats(transcriptPath)
+		lines, offset, err = transcript.ReadFrom(transcriptPath, 0)
+	}
+	if err != nil && len(lines) == 0 {
+		return nil
+	}
+
+	stats.Add(lines)
+	stats.Offset = offset
+	saveUsage(sessionID, stats)
+	return stats
+}
+
+// Add 將新的 transcript 行累加進統計
+func (s *UsageStats) Add(lines []transcript.Line) {
+	for _, l := range lines {
+		if l.Parsed == nil {
+			continue
+		}
+		msg, ok := l.Parsed["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].([]interface{})
+
+		for _, block := range content {
+			blockMap, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			blockType, _ := blockMap["type"].(string)
+
+			if role == "assistant" && blockType == "tool_use" {
+				toolID, _ := blockMap["id"].(string)
+				name, _ := blockMap["name"].(string)
+				if toolID == "" || name == "" {
+					continue
+				}
+				s.stat(name).Calls++
+				if _, exists := s.Pending[toolID]; !exists && len(s.Pending) >= maxPending {
+					s.evictOldestPending()
+				}
+				s.Pending[toolID] = &pendingCall{Name: name, StartMs: l.UnixMilli()}
+			}
+
+			if role == "user" && blockType == "tool_result" {
+				toolID, _ := blockMap["tool_use_id"].(string)
+				call, ok := s.Pending[toolID]
+				if !ok {
+					continue
+				}
+				delete(s.Pending, toolID)
+
+				stat := s.stat(call.Name)
+				if isErr, _ := blockMap["is_error"].(bool); isErr {
+					stat.Errors++
+				}
+				if endMs := l.UnixMilli(); call.StartMs > 0 && endMs >= call.StartMs {
+					stat.TotalDurationMs += endMs - call.StartMs
+					stat.TimedCalls++
+				}
+			}
+		}
+	}
+}
+
+// evictOldestPending 移除最早開始的未配對呼叫（沒有時間戳的視為最早）
+func (s *UsageStats) evictOldestPending() {
+	oldest := ""
+	for id, call := range s.Pending {
+		if o := s.Pending[oldest]; oldest == "" || call.StartMs < o.StartMs || (call.StartMs == o.StartMs && id < oldest) {
+			oldest = id
+		}
+	}
+	delete(s.Pending, oldest)
+}
+
+func (s *UsageStats) stat(name string) *ToolStat {
+	st, ok := s.Tools[name]
+	if !ok {
+		st = &ToolStat{}
+		s.Tools[name] = st
+	}
+	return st
+}
+
+// FormatUsage 格式化工具使用統計為精簡字串（如 "Read×31 Edit×14 Bash×9(2✗) 🔌github×5"），
+// MCP 工具依 server 合併計數；依呼叫次數由多到少排列，最多 MaxUsageTools 個
+func FormatUsage(stats *UsageStats) string {
+	if stats == nil || len(stats.Tools) == 0 {
+		return ""
+	}
+
+	type entry struct {
+		label         string
+		calls, errors int
+	}
+	byLabel := make(map[string]*entry)
+	for name, st := range stats.Tools {
+		label := name
+		if server, _, ok := ParseMCPName(name); ok {
+			label = MCPIcon + serverLabel(server)
+		}
+		e, ok := byLabel[label]
+		if !ok {
+			e = &entry{label: label}
+			byLabel[label] = e
+		}
+		e.calls += st.Calls
+		e.errors += st.Errors
+	}
+
+	entries := make([]*entry, 0, len(byLabel))
+	for _, e := range byLabel {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].calls != entries[j].calls {
+			return entries[i].calls > entries[j].calls
+		}
+		return entries[i].label < entries[j].label
+	})
+	if len(entries) > MaxUsageTools {
+		entries = entries[:MaxUsageTools]
+	}
+
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		part := fmt.Sprintf("%s×%d", e.label, e.calls)
+		if e.errors > 0 {
+			part += fmt.Sprintf("(%d✗)", e.errors)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func getUsageCachePath(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", fmt.Sprintf("toolstats-%s.json", sessionID))
+}
+
+func loadUsage(sessionID string) *UsageStats {
+	path := getUsageCachePath(sessionID)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var stats UsageStats
+	if err := json.Unmarshal(data, &stats); err != nil || stats.Tools == nil {
+		return nil
+	}
+	if stats.Pending == nil {
+		stats.Pending = make(map[string]*pendingCall)
+	}
+	return &stats
+}
+
+func saveUsage(sessionID string, stats *UsageStats) {
+	path := getUsageCachePath(sessionID)
+	if path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, 0644)
+}
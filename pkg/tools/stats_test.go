@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/howie/claude-code-omystatusline/pkg/transcript"
+)
+
+// toolUseLine 產生含單一 tool_use 的 transcript JSON 行
+func toolUseLine(id, name, ts string) string {
+	return fmt.Sprintf(`{"timestamp":%q,"message":{"role":"assistant","content":[{"type":"tool_use","id":%q,"name":%q,"input":{}}]}}`, ts, id, name)
+}
+
+// toolResultLine 產生含單一 tool_result 的 transcript JSON 行
+func toolResultLine(id, ts string, isError bool) string {
+	return fmt.Sprintf(`{"timestamp":%q,"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":%q,"is_error":%v}]}}`, ts, id, isError)
+}
+
+func parseLines(t *testing.T, raws ...string) []transcript.Line {
+	t.Helper()
+	lines := make([]transcript.Line, 0, len(raws))
+	for _, raw := range raws {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, transcript.Line{Raw: raw, Parsed: parsed})
+	}
+	return lines
+}
+
+func TestUsageStatsAdd(t *testing.T) {
+	stats := newUsageStats("t.jsonl")
+	stats.Add(parseLines(t,
+		toolUseLine("a", "Bash", "2026-01-01T00:00:00Z"),
+		toolResultLine("a", "2026-01-01T00:00:04Z", true),
+		toolUseLine("b", "Bash", "2026-01-01T00:00:10Z"),
+		toolResultLine("b", "2026-01-01T00:00:12Z", false),
+		toolUseLine("c", "Read", "2026-01-01T00:00:20Z"),
+	))
+
+	bash := stats.Tools["Bash"]
+	if bash == nil || bash.Calls != 2 || bash.Errors != 1 {
+		t.Fatalf("unexpected Bash stats: %+v", bash)
+	}
+	if avg := bash.AvgDurationMs(); avg != 3000 {
+		t.Errorf("expected Bash avg 3000ms, got %d", avg)
+	}
+	if read := stats.Tools["Read"]; read == nil || read.Calls != 1 || read.TimedCalls != 0 {
+		t.Errorf("unexpected Read stats: %+v", read)
+	}
+	if _, ok := stats.Pending["c"]; !ok {
+		t.Error("expected Read call to stay pending")
+	}
+
+	// 下一批的 tool_result 仍能與上一批的 tool_use 配對
+	stats.Add(parseLines(t, toolResultLine("c", "2026-01-01T00:00:21Z", false)))
+	if read := stats.Tools["Read"]; read.TimedCalls != 1 || read.AvgDurationMs() != 1000 {
+		t.Errorf("expected Read paired across batches, got %+v", read)
+	}
+}
+
+func TestUsageStatsEvictsOldestPending(t *testing.T) {
+	stats := newUsageStats("t.jsonl")
+	// 被中斷的呼叫累積到上限
+	var raws []string
+	for i := 0; i < maxPending; i++ {
+		raws = append(raws, toolUseLine(fmt.Sprintf("stale%d", i), "Bash", fmt.Sprintf("2026-01-01T00:%02d:%02dZ", i/60, i%60)))
+	}
+	stats.Add(parseLines(t, raws...))
+
+	stats.Add(parseLines(t,
+		toolUseLine("new", "Read", "2026-01-01T01:00:00Z"),
+		toolResultLine("new", "2026-01-01T01:00:02Z", true),
+	))
+	if read := stats.Tools["Read"]; read == nil || read.Errors != 1 || read.TimedCalls != 1 {
+		t.Errorf("expected new call paired after pending limit, got %+v", read)
+	}
+	if _, ok := stats.Pending["stale0"]; ok {
+		t.Error("expected oldest pending call to be evicted")
+	}
+	if _, ok := stats.Pending["stale1"]; !ok || len(stats.Pending) != maxPending-1 {
+		t.Errorf("expected only the oldest call evicted, %d pending", len(stats.Pending))
+	}
+}
+
+func TestFormatUsage(t *testing.T) {
+	stats := newUsageStats("t.jsonl")
+	stats.Tools["Edit"] = &ToolStat{Calls: 14}
+	stats.Tools["Bash"] = &ToolStat{Calls: 9, Errors: 2}
+	stats.Tools["Read"] = &ToolStat{Calls: 31}
+
+	if got, want := FormatUsage(stats), "Read×31 Edit×14 Bash×9(2✗)"; got != want {
+		t.Errorf("FormatUsage = %q, want %q", got, want)
+	}
+	if got := FormatUsage(nil); got != "" {
+		t.Errorf("expected empty string for nil stats, got %q", got)
+	}
+}
+
+func TestSessionUsageIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+
+	write := func(lines ...string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(toolUseLine("a", "Edit", "2026-01-01T00:00:00Z"), toolResultLine("a", "2026-01-01T00:00:01Z", false))
+	stats := SessionUsage(path, "sess")
+	if stats == nil || stats.Tools["Edit"].Calls != 1 {
+		t.Fatalf("unexpected first scan: %+v", stats)
+	}
+
+	// 第二次只掃描新增的行，累加到快取的統計上
+	write(toolUseLine("b", "Edit", "2026-01-01T00:00:05Z"), toolResultLine("b", "2026-01-01T00:00:06Z", true))
+	stats = SessionUsage(path, "sess")
+	if edit := stats.Tools["Edit"]; edit.Calls != 2 || edit.Errors != 1 {
+		t.Fatalf("expected cumulative Edit stats, got %+v", edit)
+	}
+
+	// transcript 被重寫時重新計算
+	if err := os.WriteFile(path, []byte(toolUseLine("c", "Grep", "2026-01-01T00:00:00Z")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats = SessionUsage(path, "sess")
+	if _, ok := stats.Tools["Edit"]; ok || stats.Tools["Grep"] == nil {
+		t.Errorf("expected stats reset after truncation, got %+v", stats.Tools)
+	}
+}
+
+func TestToolStatJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]*ToolStat{"Bash": {Calls: 2, Errors: 1, TotalDurationMs: 3000, TimedCalls: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"avg_duration_ms":1500`) || !strings.Contains(string(data), `"errors":1`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
@@ -0,0 +1,373 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+)
+
+// Extractor 從工具輸入產生顯示用的目標字串；workDir 用於將路徑轉為相對路徑
+type Extractor func(input map[string]interface{}, workDir string) string
+
+// maxTargetLen 目標字串的最大顯示字元數
+const maxTargetLen = 30
+
+// maxDescLen Bash description 的最大顯示字元數
+const maxDescLen = 24
+
+// extractors 內建與使用者註冊的工具目標萃取器（key 為工具名稱）。
+// 只在啟動時（main 載入設定後）寫入，之後僅讀取。Edit 需要預先推算的行號，由 extractTarget 處理。
+var extractors = map[string]Extractor{
+	"Bash":         bashTarget,
+	"Grep":         grepTarget,
+	"Glob":         globTarget,
+	"WebFetch":     webFetchTarget,
+	"WebSearch":    fieldTarget("query"),
+	"Read":         readTarget,
+	"Write":        pathTarget("file_path"),
+	"MultiEdit":    multiEditTarget,
+	"NotebookEdit": notebookTarget,
+	"Task":         agentTarget,
+	"Agent":        agentTarget,
+}
+
+// RegisterExtractor 註冊（或覆蓋）指定工具的目標萃取器
+func RegisterExtractor(name string, fn Extractor) {
+	extractors[name] = fn
+}
+
+// templateField 匹配模板中的 {field} 佔位符
+var templateField = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// TemplateExtractor 以 "{field}" 佔位符模板建立萃取器（供設定檔 tool_targets 使用），
+// 例如 "{database}: {sql}"；欄位不存在時替換為空字串，所有欄位都不存在時不顯示目標。
+func TemplateExtractor(tmpl string) Extractor {
+	return func(input map[string]interface{}, workDir string) string {
+		filled := false
+		out := templateField.ReplaceAllStringFunc(tmpl, func(m string) string {
+			key := m[1 : len(m)-1]
+			var val string
+			switch v := input[key].(type) {
+			case string:
+				val = v
+				if looksLikePath(key) {
+					val = relPath(v, workDir)
+				}
+			case float64:
+				val = fmt.Sprintf("%g", v)
+			case bool:
+				val = fmt.Sprintf("%t", v)
+			}
+			if val != "" {
+				filled = true
+			}
+			return val
+		})
+		if !filled {
+			return ""
+		}
+		return strings.TrimSpace(out)
+	}
+}
+
+// extractTarget 依工具類型產生目標字串，沒有專屬萃取器時使用常見欄位；
+// start/end 為 editLines 推算的 Edit 行號範圍
+func extractTarget(name string, input map[string]interface{}, workDir string, start, end int) string {
+	if input == nil {
+		return ""
+	}
+	var target string
+	if fn, ok := extractors[name]; ok {
+		target = fn(input, workDir)
+	} else if name == "Edit" {
+		target = editTarget(input, workDir, start, end)
+	} else {
+		target = defaultTarget(input, workDir)
+	}
+	return truncateTarget(target, maxTargetLen)
+}
+
+// defaultTarget 取第一個存在的常見欄位
+func defaultTarget(input map[string]interface{}, workDir string) string {
+	for _, key := range []string{"file_path", "path", "command", "pattern", "url"} {
+		if val, ok := input[key].(string); ok && val != "" {
+			if looksLikePath(key) {
+				return relPath(val, workDir)
+			}
+			return val
+		}
+	}
+	return ""
+}
+
+// bashTarget 顯示指令動詞，有 description 時附上：go · Run unit tests
+func bashTarget(input map[string]interface{}, _ string) string {
+	command, _ := input["command"].(string)
+	verb := commandVerb(command)
+	desc, _ := input["description"].(string)
+	desc = strings.TrimSpace(desc)
+	switch {
+	case verb != "" && desc != "":
+		return verb + " · " + statusline.Truncate(desc, maxDescLen, "…")
+	case desc != "":
+		return statusline.Truncate(desc, maxDescLen, "…")
+	}
+	return verb
+}
+
+// commandVerb 取出 shell 指令的第一個程式名稱，略過環境變數設定與 sudo/env 等前綴
+func commandVerb(command string) string {
+	for _, field := range strings.Fields(command) {
+		if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+			continue
+		}
+		switch field {
+		case "sudo", "env", "time", "nohup", "exec":
+			continue
+		}
+		return filepath.Base(field)
+	}
+	return ""
+}
+
+// grepTarget 顯示 pattern 與 glob/type/path 篩選："TODO" *.go
+func grepTarget(input map[string]interface{}, workDir string) string {
+	pattern, _ := input["pattern"].(string)
+	target := fmt.Sprintf("%q", pattern)
+	for _, key := range []string{"glob", "type"} {
+		if v, ok := input[key].(string); ok && v != "" {
+			return target + " " + v
+		}
+	}
+	if p, ok := input["path"].(string); ok && p != "" {
+		return target + " " + relPath(p, workDir)
+	}
+	return target
+}
+
+// globTarget 顯示 pattern，有指定目錄時附上相對路徑
+func globTarget(input map[string]interface{}, workDir string) string {
+	pattern, _ := input["pattern"].(string)
+	if p, ok := input["path"].(string); ok && p != "" {
+		return filepath.Join(relPath(p, workDir), pattern)
+	}
+	return pattern
+}
+
+// webFetchTarget 只顯示主機名稱
+func webFetchTarget(input map[string]interface{}, _ string) string {
+	raw, _ := input["url"].(string)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return u.Host
+}
+
+// readTarget 顯示相對路徑，有 offset/limit 時附上行號範圍
+func readTarget(input map[string]interface{}, workDir string) string {
+	path, _ := input["file_path"].(string)
+	target := relPath(path, workDir)
+	offset, _ := input["offset"].(float64)
+	limit, _ := input["limit"].(float64)
+	switch {
+	case offset > 0 && limit > 0:
+		return fmt.Sprintf("%s:%d-%d", target, int(offset), int(offset+limit)-1)
+	case offset > 0:
+		return fmt.Sprintf("%s:%d", target, int(offset))
+	}
+	return target
+}
+
+// editTarget 顯示 file:起始-結束行；行號由 editLines 推得，為 0 時只顯示檔案
+func editTarget(input map[string]interface{}, workDir string, start, end int) string {
+	path, _ := input["file_path"].(string)
+	target := relPath(path, workDir)
+	if start > 0 {
+		if start == end {
+			return fmt.Sprintf("%s:%d", target, start)
+		}
+		return fmt.Sprintf("%s:%d-%d", target, start, end)
+	}
+	return target
+}
+
+// multiEditTarget 顯示檔案與編輯數
+func multiEditTarget(input map[string]interface{}, workDir string) string {
+	path, _ := input["file_path"].(string)
+	target := relPath(path, workDir)
+	if edits, ok := input["edits"].([]interface{}); ok && len(edits) > 0 {
+		return fmt.Sprintf("%s ×%d", target, len(edits))
+	}
+	return target
+}
+
+// notebookTarget 顯示 notebook 與 cell（insert/delete 時加上模式）
+func notebookTarget(input map[string]interface{}, workDir string) string {
+	path, _ := input["notebook_path"].(string)
+	target := relPath(path, workDir)
+	cell, _ := input["cell_id"].(string)
+	if cell == "" {
+		if n, ok := input["cell_number"].(float64); ok {
+			cell = fmt.Sprintf("%d", int(n))
+		}
+	}
+	if cell != "" {
+		target += "#" + cell
+	}
+	if mode, _ := input["edit_mode"].(string); mode != "" && mode != "replace" {
+		target += " (" + mode + ")"
+	}
+	return target
+}
+
+// agentTarget 顯示 subagent 類型，沒有時退回 description
+func agentTarget(input map[string]interface{}, _ string) string {
+	if t, ok := input["subagent_type"].(string); ok && t != "" {
+		return t
+	}
+	desc, _ := input["description"].(string)
+	return desc
+}
+
+// targetURL 回傳工具目標檔案的 file:// 連結（Read 的 offset 或 Edit 的起始行 editStart 作為行號）；
+// 目標不是檔案時回傳空字串
+func targetURL(name string, input map[string]interface{}, workDir string, editStart int) string {
+	path, _ := input["file_path"].(string)
+	if path == "" {
+		path, _ = input["notebook_path"].(string)
+	}
+	if path == "" {
+		return ""
+	}
+	path = absPath(path, workDir)
+
+	line := 0
+	switch name {
+	case "Read":
+		if offset, ok := input["offset"].(float64); ok && offset > 0 {
+			line = int(offset)
+		}
+	case "Edit":
+		line = editStart
+	}
+	return statusline.FileURL(path, line)
+}
+
+// editLines 推算 Edit 的 old_string 在目前檔案中的行號範圍（其他工具回傳 0, 0）。
+// 需要讀檔，每個工具只呼叫一次，結果同時供目標字串與連結使用。
+func editLines(name string, input map[string]interface{}, workDir string) (start, end int) {
+	if name != "Edit" || input == nil {
+		return 0, 0
+	}
+	path, _ := input["file_path"].(string)
+	oldStr, _ := input["old_string"].(string)
+	return lineRange(absPath(path, workDir), oldStr)
+}
+
+// fieldTarget 建立直接顯示單一欄位的萃取器
+func fieldTarget(key string) Extractor {
+	return func(input map[string]interface{}, _ string) string {
+		v, _ := input[key].(string)
+		return v
+	}
+}
+
+// pathTarget 建立顯示單一路徑欄位（相對路徑）的萃取器
+func pathTarget(key string) Extractor {
+	return func(input map[string]interface{}, workDir string) string {
+		v, _ := input[key].(string)
+		return relPath(v, workDir)
+	}
+}
+
+// looksLikePath 判斷欄位名稱是否代表路徑
+func looksLikePath(key string) bool {
+	return key == "path" || strings.HasSuffix(key, "_path")
+}
+
+// absPath 將相對路徑解析為 workDir 底下的絕對路徑
+func absPath(path, workDir string) string {
+	if path == "" || filepath.IsAbs(path) || workDir == "" {
+		return path
+	}
+	return filepath.Join(workDir, path)
+}
+
+// relPath 將絕對路徑轉為相對 workDir 的路徑；不在 workDir 底下時以 ~ 縮短家目錄
+func relPath(path, workDir string) string {
+	if path == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	if workDir != "" {
+		if rel, err := filepath.Rel(workDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join("~", rel)
+		}
+	}
+	return path
+}
+
+// maxEditScanSize 推算 Edit 行號時讀取的檔案大小上限
+const maxEditScanSize = 2 * 1024 * 1024
+
+// lineRange 找出 needle 在檔案中第一次出現的起訖行號（1-based），找不到時回傳 0, 0
+func lineRange(path, needle string) (start, end int) {
+	if path == "" || needle == "" {
+		return 0, 0
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxEditScanSize {
+		return 0, 0
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+	content := string(data)
+	idx := strings.Index(content, needle)
+	if idx < 0 {
+		return 0, 0
+	}
+	start = strings.Count(content[:idx], "\n") + 1
+	end = start + strings.Count(strings.TrimSuffix(needle, "\n"), "\n")
+	return start, end
+}
+
+// truncateTarget 依顯示寬度截斷目標字串；路徑優先保留檔名
+func truncateTarget(s string, maxLen int) string {
+	if statusline.VisibleWidth(s) <= maxLen {
+		return s
+	}
+	if strings.Contains(s, "/") && !strings.Contains(s, " ") {
+		return truncatePath(s, maxLen)
+	}
+	return statusline.Truncate(s, maxLen, "…")
+}
+
+// truncatePath 截斷路徑顯示
+func truncatePath(path string, maxLen int) string {
+	if statusline.VisibleWidth(path) <= maxLen {
+		return path
+	}
+
+	// 嘗試只保留檔名（Edit/Read 的 :行號 會一併保留）
+	parts := strings.Split(path, "/")
+	if len(parts) > 1 {
+		short := fmt.Sprintf(".../%s", parts[len(parts)-1])
+		if statusline.VisibleWidth(short) <= maxLen {
+			return short
+		}
+	}
+
+	return statusline.Truncate(path, maxLen, "...")
+}
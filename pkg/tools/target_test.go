@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
+)
+
+func TestExtractTargetPerTool(t *testing.T) {
+	workDir := "/work/proj"
+	tests := []struct {
+		tool  string
+		input map[string]interface{}
+		want  string
+	}{
+		{"Bash", map[string]interface{}{"command": "go test ./...", "description": "Run unit tests"}, "go · Run unit tests"},
+		{"Bash", map[string]interface{}{"command": "CGO_ENABLED=0 sudo /usr/bin/make build"}, "make"},
+		{"Grep", map[string]interface{}{"pattern": "TODO", "glob": "*.go"}, `"TODO" *.go`},
+		{"Grep", map[string]interface{}{"pattern": "fix", "path": "/work/proj/pkg"}, `"fix" pkg`},
+		{"Glob", map[string]interface{}{"pattern": "**/*.md", "path": "/work/proj/docs"}, "docs/**/*.md"},
+		{"WebFetch", map[string]interface{}{"url": "https://docs.example.com/a/b?c=d"}, "docs.example.com"},
+		{"Read", map[string]interface{}{"file_path": "/work/proj/main.go", "offset": float64(10), "limit": float64(20)}, "main.go:10-29"},
+		{"Write", map[string]interface{}{"file_path": "/work/proj/pkg/a.go"}, "pkg/a.go"},
+		{"MultiEdit", map[string]interface{}{"file_path": "/work/proj/a.go", "edits": []interface{}{1, 2}}, "a.go ×2"},
+		{"Task", map[string]interface{}{"subagent_type": "Explore", "description": "find"}, "Explore"},
+		{"Agent", map[string]interface{}{"description": "review code"}, "review code"},
+		{"NotebookEdit", map[string]interface{}{"notebook_path": "/work/proj/nb.ipynb", "cell_id": "abc", "edit_mode": "insert"}, "nb.ipynb#abc (insert)"},
+		{"Custom", map[string]interface{}{"path": "/other/place/file.txt"}, "/other/place/file.txt"},
+	}
+	for _, tt := range tests {
+		if got := extractTarget(tt.tool, tt.input, workDir, 0, 0); got != tt.want {
+			t.Errorf("%s %v: got %q, want %q", tt.tool, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEditTargetLineRange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	content := "package main\n\nfunc main() {\n\tprintln(1)\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := map[string]interface{}{"file_path": path, "old_string": "func main() {\n\tprintln(1)\n}"}
+	start, end := editLines("Edit", input, dir)
+	if got := extractTarget("Edit", input, dir, start, end); got != "main.go:3-5" {
+		t.Errorf("expected main.go:3-5, got %q", got)
+	}
+
+	// 相對路徑以 workDir 解析，而非 statusline 行程的工作目錄
+	input["file_path"] = "main.go"
+	if start, end := editLines("Edit", input, dir); start != 3 || end != 5 {
+		t.Errorf("expected relative path resolved against workDir, got %d-%d", start, end)
+	}
+
+	input["old_string"] = "not present"
+	start, end = editLines("Edit", input, dir)
+	if got := extractTarget("Edit", input, dir, start, end); got != "main.go" {
+		t.Errorf("expected bare file when old_string not found, got %q", got)
+	}
+}
+
+func TestTargetURL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := targetURL("Edit", map[string]interface{}{"file_path": path}, dir, 3), statusline.FileURL(path, 3); got != want {
+		t.Errorf("Edit url = %q, want %q", got, want)
+	}
+	if got, want := targetURL("Read", map[string]interface{}{"file_path": "main.go", "offset": float64(2)}, dir, 0), statusline.FileURL(path, 2); got != want {
+		t.Errorf("Read url = %q, want %q", got, want)
+	}
+	if got := targetURL("Bash", map[string]interface{}{"command": "ls"}, dir, 0); got != "" {
+		t.Errorf("Bash should have no url, got %q", got)
+	}
+}
+
+func TestFormatHyperlinkedTarget(t *testing.T) {
+	orig := statusline.HyperlinksEnabled
+	defer func() { statusline.HyperlinksEnabled = orig }()
+
+	tools := []ToolInfo{{Name: "Read", Target: "main.go", TargetURL: "file:///w/main.go"}}
+	statusline.HyperlinksEnabled = true
+	if got := Format(tools); !strings.Contains(got, "\033]8;;file:///w/main.go\033\\main.go") {
+		t.Errorf("expected hyperlinked target, got %q", got)
+	}
+	statusline.HyperlinksEnabled = false
+	if got := Format(tools); got != "◐ Read: main.go" {
+		t.Errorf("expected plain target, got %q", got)
+	}
+}
+
+func TestTemplateExtractor(t *testing.T) {
+	RegisterExtractor("mcp__db__query", TemplateExtractor("{database}: {sql}"))
+	defer delete(extractors, "mcp__db__query")
+
+	input := map[string]interface{}{"database": "prod", "sql": "select 1"}
+	if got := extractTarget("mcp__db__query", input, "", 0, 0); got != "prod: select 1" {
+		t.Errorf("expected template target, got %q", got)
+	}
+	if got := extractTarget("mcp__db__query", map[string]interface{}{}, "", 0, 0); got != "" {
+		t.Errorf("expected no target when no field is present, got %q", got)
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	tests := []struct{ path, workDir, want string }{
+		{"/work/proj/a/b.go", "/work/proj", "a/b.go"},
+		{"/work/project2/x.go", "/work/proj", "/work/project2/x.go"},
+		{"/home/me/notes.md", "/work/proj", "~/notes.md"},
+		{"relative/x.go", "/work/proj", "relative/x.go"},
+	}
+	for _, tt := range tests {
+		if got := relPath(tt.path, tt.workDir); got != tt.want {
+			t.Errorf("relPath(%q, %q) = %q, want %q", tt.path, tt.workDir, got, tt.want)
+		}
+	}
+}
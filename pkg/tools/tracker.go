@@ -3,26 +3,38 @@ package tools
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/howie/claude-code-omystatusline/pkg/statusline"
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
 
 // ToolInfo 代表一個正在執行的工具
 type ToolInfo struct {
-	Name   string
-	Target string // 截斷的路徑或參數
+	Name       string
+	Target     string    // 截斷的路徑或參數
+	TargetURL  string    // 目標檔案的 file:// 連結；僅在啟用超連結時計算
+	StartTime  time.Time // tool_use 所在行的 timestamp；transcript 沒有時為零值
+	ElapsedSec int
+	Slow       bool // 執行時間超過警告門檻（由 MarkSlow 設定）
 }
 
 // MaxTools 最多顯示的工具數量
 const MaxTools = 2
 
-// Analyze 從 transcript 行中找出正在執行的工具
-func Analyze(lines []transcript.Line) []ToolInfo {
+// DefaultThresholdKey 警告門檻中代表「其他所有工具」的 key
+const DefaultThresholdKey = "*"
+
+// Analyze 從 transcript 行中找出正在執行的工具；workDir 用於將目標路徑顯示為相對路徑
+func Analyze(lines []transcript.Line, workDir string) []ToolInfo {
 	// 追蹤工具狀態：tool_use 開始，tool_result 結束
 	// 使用 toolUseId 來匹配
-	activeTools := make(map[string]ToolInfo) // toolUseId -> ToolInfo
-	completedTools := make(map[string]bool)  // 已完成的 toolUseId
-	var toolOrder []string                   // 保持順序
+	activeTools := make(map[string]ToolInfo)              // toolUseId -> ToolInfo
+	toolInputs := make(map[string]map[string]interface{}) // toolUseId -> input，只替執行中的工具萃取目標
+	completedTools := make(map[string]bool)               // 已完成的 toolUseId
+	var toolOrder []string                                // 保持順序
+
+	now := time.Now()
 
 	for _, l := range lines {
 		if l.Parsed == nil {
@@ -52,8 +64,8 @@ func Analyze(lines []transcript.Line) []ToolInfo {
 					continue
 				}
 
-				target := extractTarget(blockMap)
-				activeTools[toolID] = ToolInfo{Name: name, Target: target}
+				activeTools[toolID] = ToolInfo{Name: name, StartTime: l.Time()}
+				toolInputs[toolID], _ = blockMap["input"].(map[string]interface{})
 				toolOrder = append(toolOrder, toolID)
 			}
 
@@ -71,7 +83,20 @@ func Analyze(lines []transcript.Line) []ToolInfo {
 	for i := len(toolOrder) - 1; i >= 0; i-- {
 		id := toolOrder[i]
 		if !completedTools[id] {
-			running = append(running, activeTools[id])
+			tool := activeTools[id]
+			input := toolInputs[id]
+			start, end := editLines(tool.Name, input, workDir)
+			tool.Target = extractTarget(tool.Name, input, workDir, start, end)
+			if statusline.HyperlinksEnabled && tool.Target != "" {
+				tool.TargetURL = targetURL(tool.Name, input, workDir, start)
+			}
+			if !tool.StartTime.IsZero() {
+				tool.ElapsedSec = int(now.Sub(tool.StartTime).Seconds())
+				if tool.ElapsedSec < 0 {
+					tool.ElapsedSec = 0
+				}
+			}
+			running = append(running, tool)
 			if len(running) >= MaxTools {
 				break
 			}
@@ -86,39 +111,21 @@ func Analyze(lines []transcript.Line) []ToolInfo {
 	return running
 }
 
-// extractTarget 從工具輸入中提取目標路徑
-func extractTarget(block map[string]interface{}) string {
-	input, ok := block["input"].(map[string]interface{})
-	if !ok {
-		return ""
-	}
-
-	// 常見的路徑欄位
-	for _, key := range []string{"file_path", "path", "command", "pattern", "url"} {
-		if val, ok := input[key].(string); ok && val != "" {
-			return truncatePath(val, 30)
+// MarkSlow 依工具名稱的秒數門檻標記執行過久的工具。
+// 查找順序：完整工具名稱、MCP server（"mcp__<server>"）、DefaultThresholdKey；門檻 <= 0 代表不警告。
+func MarkSlow(tools []ToolInfo, thresholds map[string]int) {
+	for i := range tools {
+		limit, ok := thresholds[tools[i].Name]
+		if !ok {
+			if server, _, isMCP := ParseMCPName(tools[i].Name); isMCP {
+				limit, ok = thresholds[mcpPrefix+server]
+			}
 		}
-	}
-
-	return ""
-}
-
-// truncatePath 截斷路徑顯示
-func truncatePath(path string, maxLen int) string {
-	if len(path) <= maxLen {
-		return path
-	}
-
-	// 嘗試只保留檔名
-	parts := strings.Split(path, "/")
-	if len(parts) > 1 {
-		short := fmt.Sprintf(".../%s", parts[len(parts)-1])
-		if len(short) <= maxLen {
-			return short
+		if !ok {
+			limit = thresholds[DefaultThresholdKey]
 		}
+		tools[i].Slow = limit > 0 && tools[i].ElapsedSec >= limit
 	}
-
-	return path[:maxLen-3] + "..."
 }
 
 // Format 格式化工具列表為顯示字串
@@ -129,12 +136,34 @@ func Format(tools []ToolInfo) string {
 
 	var parts []string
 	for _, t := range tools {
+		icon := "◐"
+		if t.Slow {
+			icon = "⚠"
+		}
+		part := fmt.Sprintf("%s %s", icon, DisplayName(t.Name))
 		if t.Target != "" {
-			parts = append(parts, fmt.Sprintf("◐ %s: %s", t.Name, t.Target))
-		} else {
-			parts = append(parts, fmt.Sprintf("◐ %s", t.Name))
+			part += ": " + statusline.Hyperlink(t.TargetURL, t.Target)
 		}
+		if t.ElapsedSec > 0 {
+			part += " " + formatElapsed(t.ElapsedSec)
+		}
+		if t.Slow {
+			part = statusline.ColorRed + part + statusline.ColorReset
+		}
+		parts = append(parts, part)
 	}
 
 	return strings.Join(parts, "  ")
 }
+
+func formatElapsed(seconds int) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	m := seconds / 60
+	s := seconds % 60
+	if s == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dm%ds", m, s)
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/howie/claude-code-omystatusline/pkg/transcript"
 )
@@ -11,7 +12,7 @@ func TestAnalyzeNoTools(t *testing.T) {
 	lines := []transcript.Line{
 		{Parsed: map[string]interface{}{"type": "user"}},
 	}
-	result := Analyze(lines)
+	result := Analyze(lines, "")
 	if len(result) != 0 {
 		t.Fatalf("expected no tools, got %d", len(result))
 		return
@@ -50,7 +51,7 @@ func TestAnalyzeActiveTools(t *testing.T) {
 		}},
 	}
 
-	result := Analyze(lines)
+	result := Analyze(lines, "")
 	if len(result) != 2 {
 		t.Fatalf("expected 2 active tools, got %d", len(result))
 		return
@@ -93,7 +94,7 @@ func TestAnalyzeCompletedTool(t *testing.T) {
 		}},
 	}
 
-	result := Analyze(lines)
+	result := Analyze(lines, "")
 	if len(result) != 0 {
 		t.Fatalf("expected no active tools (tool completed), got %d", len(result))
 		return
@@ -117,6 +118,67 @@ func TestFormat(t *testing.T) {
 	}
 }
 
+func TestAnalyzeElapsed(t *testing.T) {
+	start := time.Now().Add(-150 * time.Second).UTC().Format(time.RFC3339)
+	lines := []transcript.Line{
+		{Parsed: map[string]interface{}{
+			"timestamp": start,
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"content": []interface{}{
+					map[string]interface{}{
+						"type":  "tool_use",
+						"id":    "tool1",
+						"name":  "Bash",
+						"input": map[string]interface{}{"command": "go test ./..."},
+					},
+				},
+			},
+		}},
+	}
+
+	result := Analyze(lines, "")
+	if len(result) != 1 {
+		t.Fatalf("expected 1 active tool, got %d", len(result))
+	}
+	if result[0].StartTime.IsZero() {
+		t.Fatal("expected StartTime from transcript timestamp")
+	}
+	if result[0].ElapsedSec < 149 || result[0].ElapsedSec > 160 {
+		t.Errorf("expected elapsed ~150s, got %d", result[0].ElapsedSec)
+	}
+}
+
+func TestMarkSlow(t *testing.T) {
+	tools := []ToolInfo{
+		{Name: "Bash", ElapsedSec: 130},
+		{Name: "Read", ElapsedSec: 130},
+		{Name: "Grep", ElapsedSec: 400},
+		{Name: "WebFetch", ElapsedSec: 400},
+	}
+	MarkSlow(tools, map[string]int{"Bash": 120, "WebFetch": 0, "*": 300})
+
+	want := []bool{true, false, true, false}
+	for i, tool := range tools {
+		if tool.Slow != want[i] {
+			t.Errorf("%s: expected Slow=%v, got %v", tool.Name, want[i], tool.Slow)
+		}
+	}
+}
+
+func TestFormatElapsedAndSlow(t *testing.T) {
+	result := Format([]ToolInfo{
+		{Name: "Read", Target: "/src/main.go", ElapsedSec: 3},
+		{Name: "Bash", Target: "make", ElapsedSec: 135, Slow: true},
+	})
+	if !strings.Contains(result, "◐ Read: /src/main.go 3s") {
+		t.Errorf("expected elapsed for Read, got %q", result)
+	}
+	if !strings.Contains(result, "⚠ Bash: make 2m15s") {
+		t.Errorf("expected slow warning for Bash, got %q", result)
+	}
+}
+
 func TestTruncatePath(t *testing.T) {
 	long := "/home/user/very/long/path/to/some/deeply/nested/file.go"
 	result := truncatePath(long, 30)
@@ -2,17 +2,51 @@ package transcript
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"math"
 	"os"
+	"time"
 )
 
+// ErrTruncated 表示檔案比上次讀取的位移還短（被截斷或重寫），呼叫端應從頭重新讀取
+var ErrTruncated = errors.New("transcript: file shorter than offset")
+
 // Line 代表 transcript 中的一行
 type Line struct {
 	Raw    string
 	Parsed map[string]interface{}
 }
 
+// Time 此行的時間戳，無法解析時回傳零值
+func (l Line) Time() time.Time {
+	return Timestamp(l.Parsed)
+}
+
+// UnixMilli 此行的時間戳（毫秒），沒有時回傳 0
+func (l Line) UnixMilli() int64 {
+	t := l.Time()
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// Timestamp 從解析後的 transcript 行提取時間戳（RFC 3339 字串或毫秒數字），無法解析時回傳零值
+func Timestamp(parsed map[string]interface{}) time.Time {
+	switch ts := parsed["timestamp"].(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t
+		}
+	case float64:
+		return time.UnixMilli(int64(ts))
+	}
+	return time.Time{}
+}
+
 // ReadTail 讀取 transcript 檔案的最後 n 行並解析 JSON
 func ReadTail(path string, n int) ([]Line, error) {
 	if path == "" {
@@ -81,3 +115,54 @@ func FilterBySession(lines []Line, sessionID string) []Line {
 	}
 	return result
 }
+
+// ReadFrom 從 byte 位移 offset 開始讀取完整的行並解析 JSON，回傳下次讀取的位移。
+// 結尾尚未寫完（沒有換行）的行不會被讀取，留待下次；用於增量掃描整份 transcript。
+func ReadFrom(path string, offset int64) ([]Line, int64, error) {
+	if path == "" {
+		return nil, offset, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer func() { _ = file.Close() }()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+	if stat.Size() < offset {
+		return nil, offset, ErrTruncated
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+
+	var lines []Line
+	reader := bufio.NewReader(file)
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil {
+			// io.EOF：剩下的是不完整的行，不前進位移
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return lines, offset, err
+		}
+		offset += int64(len(raw))
+
+		raw = bytes.TrimRight(raw, "\r\n")
+		l := Line{Raw: string(raw)}
+		if len(raw) > 0 {
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(raw, &parsed); err == nil {
+				l.Parsed = parsed
+			}
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, offset, nil
+}
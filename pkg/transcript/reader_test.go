@@ -1,10 +1,12 @@
 package transcript
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadTail(t *testing.T) {
@@ -68,6 +70,49 @@ func TestReadAll(t *testing.T) {
 	}
 }
 
+func TestReadFrom(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transcript.log")
+	// 最後一行尚未寫完（沒有換行）
+	if err := os.WriteFile(path, []byte("{\"a\":1}\n{\"b\":2}\n{\"c\":"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, offset, err := ReadFrom(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || offset != int64(len("{\"a\":1}\n{\"b\":2}\n")) {
+		t.Fatalf("expected 2 complete lines, got %d (offset %d)", len(lines), offset)
+	}
+
+	// 補完最後一行後，只讀到新的內容
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("3}\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	lines, _, err = ReadFrom(path, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Parsed["c"] != float64(3) {
+		t.Fatalf("expected completed line {\"c\":3}, got %+v", lines)
+	}
+
+	// 檔案被截斷
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadFrom(path, offset); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+}
+
 func TestReadTailEmptyPath(t *testing.T) {
 	result, err := ReadTail("", 10)
 	if err != nil {
@@ -95,3 +140,30 @@ func TestFilterBySession(t *testing.T) {
 		return
 	}
 }
+
+func TestLineTime(t *testing.T) {
+	want := time.Date(2026, 1, 1, 12, 0, 3, 500_000_000, time.UTC)
+	tests := []struct {
+		name   string
+		parsed map[string]interface{}
+		want   time.Time
+	}{
+		{"rfc3339", map[string]interface{}{"timestamp": "2026-01-01T12:00:03.5Z"}, want},
+		{"epoch ms", map[string]interface{}{"timestamp": float64(want.UnixMilli())}, want},
+		{"invalid", map[string]interface{}{"timestamp": "yesterday"}, time.Time{}},
+		{"missing", map[string]interface{}{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		l := Line{Parsed: tt.parsed}
+		if got := l.Time(); !got.Equal(tt.want) {
+			t.Errorf("%s: Time() = %v, want %v", tt.name, got, tt.want)
+		}
+		wantMs := int64(0)
+		if !tt.want.IsZero() {
+			wantMs = tt.want.UnixMilli()
+		}
+		if got := l.UnixMilli(); got != wantMs {
+			t.Errorf("%s: UnixMilli() = %d, want %d", tt.name, got, wantMs)
+		}
+	}
+}
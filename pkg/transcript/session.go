@@ -0,0 +1,229 @@
+package transcript
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Usage assistant 訊息 usage 中的三種輸入 token
+type Usage struct {
+	Uncached        int64 `json:"uncached"` // input_tokens
+	CacheRead       int64 `json:"cache_read"`
+	CacheCreation   int64 `json:"cache_creation"`
+	CacheCreation1h int64 `json:"cache_creation_1h"` // CacheCreation 中寫入 1 小時快取的部分，其餘為 5 分鐘快取
+}
+
+// Total 三種輸入 token 的總和
+func (u Usage) Total() int64 {
+	return u.Uncached + u.CacheRead + u.CacheCreation
+}
+
+// ParseUsage 讀取 message.usage 中的三種輸入 token，以及 cache_creation 分類中的 1 小時快取寫入量
+func ParseUsage(usage map[string]interface{}) Usage {
+	var u Usage
+	if v, ok := usage["input_tokens"].(float64); ok {
+		u.Uncached = int64(v)
+	}
+	if v, ok := usage["cache_read_input_tokens"].(float64); ok {
+		u.CacheRead = int64(v)
+	}
+	if v, ok := usage["cache_creation_input_tokens"].(float64); ok {
+		u.CacheCreation = int64(v)
+	}
+	if creation, ok := usage["cache_creation"].(map[string]interface{}); ok {
+		if v, ok := creation["ephemeral_1h_input_tokens"].(float64); ok {
+			u.CacheCreation1h = min(int64(v), u.CacheCreation)
+		}
+	}
+	return u
+}
+
+// RecentUsageWindow SessionScan 保留的最近主對話回應 usage 數
+const RecentUsageWindow = 5
+
+// maxRecentIDs 保留的最近 message.id 數量（同一則訊息拆成多行時會重複出現）
+const maxRecentIDs = 32
+
+// SessionScan 整個 session 的 assistant 回應統計，連同增量掃描的進度一起快取。
+// 同一則訊息拆成多行時以 message.id 去重，只計算一次。
+type SessionScan struct {
+	Path      string            `json:"path"`
+	Offset    int64             `json:"offset"`
+	Responses int               `json:"responses"`  // 不重複的 assistant 回應數（含子代理，不含 <synthetic>）
+	Models    map[string]*Usage `json:"models"`     // 依模型累計的 usage（含子代理）
+	Recent    []Usage           `json:"recent"`     // 最近 RecentUsageWindow 個主對話回應的 usage
+	RecentIDs []string          `json:"recent_ids"` // 已計算的最近 message.id
+}
+
+// NewSessionScan 建立尚未掃描的統計
+func NewSessionScan(path string) *SessionScan {
+	return &SessionScan{Path: path, Models: make(map[string]*Usage)}
+}
+
+// Add 累加新的 transcript 行中的 assistant 回應
+func (s *SessionScan) Add(lines []Line) {
+	for _, l := range lines {
+		if l.Parsed == nil {
+			continue
+		}
+		msg, ok := l.Parsed["message"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+		id, _ := msg["id"].(string)
+		if role != "assistant" || id == "" || s.seen(id) {
+			continue
+		}
+		s.RecentIDs = append(s.RecentIDs, id)
+		if len(s.RecentIDs) > maxRecentIDs {
+			s.RecentIDs = s.RecentIDs[len(s.RecentIDs)-maxRecentIDs:]
+		}
+
+		model, _ := msg["model"].(string)
+		if model != "<synthetic>" {
+			s.Responses++
+		}
+
+		usageMap, ok := msg["usage"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		usage := ParseUsage(usageMap)
+		if usage.Total() <= 0 {
+			continue
+		}
+		m, ok := s.Models[model]
+		if !ok {
+			m = &Usage{}
+			s.Models[model] = m
+		}
+		m.Uncached += usage.Uncached
+		m.CacheRead += usage.CacheRead
+		m.CacheCreation += usage.CacheCreation
+		m.CacheCreation1h += usage.CacheCreation1h
+
+		if isSide, _ := l.Parsed["isSidechain"].(bool); !isSide {
+			s.Recent = append(s.Recent, usage)
+			if len(s.Recent) > RecentUsageWindow {
+				s.Recent = s.Recent[len(s.Recent)-RecentUsageWindow:]
+			}
+		}
+	}
+}
+
+func (s *SessionScan) seen(id string) bool {
+	for _, recent := range s.RecentIDs {
+		if recent == id {
+			return true
+		}
+	}
+	return false
+}
+
+// clone 複製一份，讓呼叫端在鎖外讀取時不受之後的掃描影響
+func (s *SessionScan) clone() *SessionScan {
+	c := *s
+	c.Models = make(map[string]*Usage, len(s.Models))
+	for model, u := range s.Models {
+		usage := *u
+		c.Models[model] = &usage
+	}
+	c.Recent = append([]Usage(nil), s.Recent...)
+	c.RecentIDs = append([]string(nil), s.RecentIDs...)
+	return &c
+}
+
+// 同一行程內的掃描結果（cache 與 latency 區段並行呼叫，只需掃描一次）
+var (
+	scanMu sync.Mutex
+	scans  = make(map[string]*SessionScan)
+)
+
+// ScanSession 增量掃描整份 transcript，回傳 session 的 assistant 回應統計；無法讀取時回傳 nil。
+// 掃描進度快取在 cache 目錄，每次只解析上次之後新增的行；同一行程內的呼叫共用同一份進度。
+func ScanSession(path, sessionID string) *SessionScan {
+	if path == "" {
+		return nil
+	}
+
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	key := sessionID + "|" + path
+	scan := scans[key]
+	if scan == nil {
+		scan = loadSessionScan(sessionID)
+	}
+	if scan == nil || scan.Path != path {
+		scan = NewSessionScan(path)
+	}
+
+	lines, offset, err := ReadFrom(path, scan.Offset)
+	if errors.Is(err, ErrTruncated) {
+		scan = NewSessionScan(path)
+		lines, offset, err = ReadFrom(path, 0)
+	}
+	if err != nil && len(lines) == 0 {
+		return nil
+	}
+
+	scans[key] = scan
+	if offset != scan.Offset {
+		scan.Add(lines)
+		scan.Offset = offset
+		saveSessionScan(sessionID, scan)
+	}
+	return scan.clone()
+}
+
+func getSessionScanPath(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude", "omystatusline", "cache", fmt.Sprintf("session-%s.json", sessionID))
+}
+
+func loadSessionScan(sessionID string) *SessionScan {
+	path := getSessionScanPath(sessionID)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var scan SessionScan
+	if err := json.Unmarshal(data, &scan); err != nil || scan.Models == nil {
+		return nil
+	}
+	return &scan
+}
+
+func saveSessionScan(sessionID string, scan *SessionScan) {
+	path := getSessionScanPath(sessionID)
+	if path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(scan)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, 0644)
+}
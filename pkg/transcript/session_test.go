@@ -0,0 +1,78 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionScanAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"message":{"id":"m1","role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"cache_read_input_tokens":90}}}
+{"message":{"id":"m1","role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"cache_read_input_tokens":90}}}
+{"isSidechain":true,"message":{"id":"m2","role":"assistant","model":"claude-haiku-4-5","usage":{"input_tokens":5,"cache_creation_input_tokens":50}}}
+{"message":{"id":"m3","role":"assistant","model":"<synthetic>"}}
+{"message":{"id":"u1","role":"user","content":"hi"}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, _, err := ReadFrom(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scan := NewSessionScan(path)
+	scan.Add(lines)
+	if scan.Responses != 2 {
+		t.Errorf("expected 2 responses (split line and <synthetic> excluded), got %d", scan.Responses)
+	}
+	if u := scan.Models["claude-sonnet-4-5"]; u == nil || u.Total() != 100 {
+		t.Errorf("expected sonnet usage counted once, got %+v", u)
+	}
+	if u := scan.Models["claude-haiku-4-5"]; u == nil || u.CacheCreation != 50 {
+		t.Errorf("expected sidechain usage per model, got %+v", u)
+	}
+	if len(scan.Recent) != 1 {
+		t.Errorf("expected only main-chain usage in Recent, got %+v", scan.Recent)
+	}
+}
+
+func TestScanSessionIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	line := func(id string) string {
+		return `{"message":{"id":"` + id + `","role":"assistant","model":"claude-sonnet-4-5","usage":{"input_tokens":10}}}` + "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(line("m1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := ScanSession(path, "s1")
+	// 同一行程內的第二個呼叫者沿用進度，不重複計算
+	if second := ScanSession(path, "s1"); first == nil || second == nil || first.Responses != 1 || second.Responses != 1 {
+		t.Fatalf("unexpected scans: %+v / %+v", first, second)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(line("m1") + line("m2"))
+	f.Close()
+	if scan := ScanSession(path, "s1"); scan.Responses != 2 || scan.Models["claude-sonnet-4-5"].Uncached != 20 {
+		t.Errorf("unexpected incremental scan: %+v", scan)
+	}
+	// 回傳的是複本，不受之後的掃描影響
+	if first.Responses != 1 {
+		t.Errorf("earlier snapshot changed: %+v", first)
+	}
+
+	// 檔案被重寫時從頭掃描
+	if err := os.WriteFile(path, []byte(line("m9")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if scan := ScanSession(path, "s1"); scan.Responses != 1 {
+		t.Errorf("expected rescan after truncation, got %+v", scan)
+	}
+}
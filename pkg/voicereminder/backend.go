@@ -0,0 +1,348 @@
+package voicereminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRate 預設語速（每分鐘字數，與 macOS say 的 -r 同單位），各後端依此換算成自己的參數
+const DefaultRate = 180
+
+// 後端名稱（對應設定檔 tts.backends）
+const (
+	BackendSay      = "say"
+	BackendEspeakNG = "espeak-ng"
+	BackendEspeak   = "espeak"
+	BackendSpdSay   = "spd-say"
+	BackendFestival = "festival"
+	BackendPiper    = "piper"
+	BackendCustom   = "custom"
+)
+
+// errNoBackend 設定的後端都無法使用（未安裝或缺少設定）
+var errNoBackend = errors.New("沒有可用的語音後端")
+
+// SpeakOptions 一次語音播放的參數
+type SpeakOptions struct {
+	Language string // "zh" 或 "en"
+	Rate     int    // 每分鐘字數；<= 0 時使用 DefaultRate
+}
+
+// Backend 一種 TTS 引擎
+type Backend interface {
+	// Name 後端名稱（用於日誌）
+	Name() string
+	// Available 是否已安裝且設定完整
+	Available() bool
+	// Speak 播放語音，直到播放完畢或 ctx 逾時
+	Speak(ctx context.Context, text string, opts SpeakOptions) error
+}
+
+// TTSConfig 語音後端設定
+type TTSConfig struct {
+	// Backends 依序嘗試的後端；空時依平台使用預設鏈（macOS: say；其他: piper、espeak-ng、espeak、spd-say、festival）
+	Backends []string `json:"backends"`
+	// Voices 各後端依語言使用的語音，如 {"espeak-ng": {"zh": "cmn"}}；piper 為模型（.onnx）路徑
+	Voices map[string]map[string]string `json:"voices"`
+	// Command custom 後端的指令樣板，可用 {text}、{lang}、{rate}、{voice}，如 "mimic3 --voice {voice} {text}"
+	Command string `json:"command"`
+}
+
+// defaultChain 未設定 tts.backends 時的預設後端鏈
+func (c TTSConfig) defaultChain() []string {
+	var chain []string
+	if c.Command != "" {
+		chain = append(chain, BackendCustom)
+	}
+	if runtime.GOOS == "darwin" {
+		return append(chain, BackendSay)
+	}
+	return append(chain, BackendPiper, BackendEspeakNG, BackendEspeak, BackendSpdSay, BackendFestival)
+}
+
+// voice 取得後端在指定語言使用的語音；沒有設定時回傳 fallback
+func (c TTSConfig) voice(backend, language, fallback string) string {
+	if v := c.Voices[backend][language]; v != "" {
+		return v
+	}
+	return fallback
+}
+
+// NewBackend 依名稱建立後端
+func NewBackend(name string, cfg TTSConfig) (Backend, error) {
+	switch name {
+	case BackendSay:
+		return &sayBackend{cfg: cfg}, nil
+	case BackendEspeakNG, BackendEspeak:
+		return &espeakBackend{command: name, cfg: cfg}, nil
+	case BackendSpdSay:
+		return &spdSayBackend{cfg: cfg}, nil
+	case BackendFestival:
+		return &festivalBackend{cfg: cfg}, nil
+	case BackendPiper:
+		return &piperBackend{cfg: cfg}, nil
+	case BackendCustom:
+		return &commandBackend{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("未知的語音後端 %q", name)
+}
+
+// Backends 依 tts 設定建立後端鏈；未知的後端名稱記錄後略過
+func (c *Config) Backends(logger *Logger) []Backend {
+	names := c.TTS.Backends
+	if len(names) == 0 {
+		names = c.TTS.defaultChain()
+	}
+	var chain []Backend
+	for _, name := range names {
+		b, err := NewBackend(name, c.TTS)
+		if err != nil {
+			if logger != nil {
+				logger.Log("略過語音後端: %v", err)
+			}
+			continue
+		}
+		chain = append(chain, b)
+	}
+	return chain
+}
+
+// DefaultBackends 沒有設定檔時的預設後端鏈
+func DefaultBackends() []Backend {
+	return getDefaultConfig().Backends(nil)
+}
+
+// speakChain 依序嘗試可用的後端，第一個成功即返回。
+// 每個後端各有 timeout 的時間，卡住的後端超時終止後仍會嘗試下一個。
+func speakChain(chain []Backend, text string, opts SpeakOptions, timeout time.Duration, logger *Logger) error {
+	var errs []error
+	for _, b := range chain {
+		if !b.Available() {
+			continue
+		}
+		if logger != nil {
+			logger.Log("使用語音後端: %s", b.Name())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := b.Speak(ctx, text, opts)
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if timedOut {
+			err = fmt.Errorf("超時 (%.0f 秒)，已終止進程", timeout.Seconds())
+		}
+		if logger != nil {
+			logger.Log("語音後端 %s 失敗: %v", b.Name(), err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name(), err))
+	}
+	if len(errs) == 0 {
+		return errNoBackend
+	}
+	return errors.Join(errs...)
+}
+
+// sayBackend macOS 內建的 say
+type sayBackend struct{ cfg TTSConfig }
+
+func (b *sayBackend) Name() string    { return BackendSay }
+func (b *sayBackend) Available() bool { return hasCommand("say") }
+
+func (b *sayBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	voice := b.cfg.voice(BackendSay, opts.Language, selectVoice(opts.Language))
+	return runCommand(ctx, "say", "-v", voice, "-r", strconv.Itoa(rate(opts)), text)
+}
+
+// espeakBackend espeak-ng 或舊版 espeak（-s 與 say 同為每分鐘字數）
+type espeakBackend struct {
+	command string
+	cfg     TTSConfig
+}
+
+func (b *espeakBackend) Name() string    { return b.command }
+func (b *espeakBackend) Available() bool { return hasCommand(b.command) }
+
+func (b *espeakBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	// espeak-ng 的國語為 cmn；舊版 espeak 只有 zh
+	fallback := "en"
+	if opts.Language == "zh" {
+		fallback = "zh"
+		if b.command == BackendEspeakNG {
+			fallback = "cmn"
+		}
+	}
+	voice := b.cfg.voice(b.command, opts.Language, fallback)
+	return runCommand(ctx, b.command, "-v", voice, "-s", strconv.Itoa(rate(opts)), text)
+}
+
+// spdSayBackend speech-dispatcher 的 spd-say（-r 為 -100 到 100 的相對語速）
+type spdSayBackend struct{ cfg TTSConfig }
+
+func (b *spdSayBackend) Name() string    { return BackendSpdSay }
+func (b *spdSayBackend) Available() bool { return hasCommand("spd-say") }
+
+func (b *spdSayBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	args := []string{"--wait", "-l", spdLanguage(opts.Language), "-r", strconv.Itoa(spdRate(rate(opts)))}
+	if voice := b.cfg.voice(BackendSpdSay, opts.Language, ""); voice != "" {
+		args = append(args, "-y", voice)
+	}
+	return runCommand(ctx, "spd-say", append(args, text)...)
+}
+
+// spdLanguage 轉換為 speech-dispatcher 的語言代碼
+func spdLanguage(language string) string {
+	if language == "zh" {
+		return "zh"
+	}
+	return "en"
+}
+
+// spdRate 將每分鐘字數換算為 spd-say 的相對語速：DefaultRate 為 0，兩倍速為 100
+func spdRate(wpm int) int {
+	r := (wpm - DefaultRate) * 100 / DefaultRate
+	return max(-100, min(100, r))
+}
+
+// festivalBackend festival，以 Scheme 指令設定語音與語速
+type festivalBackend struct{ cfg TTSConfig }
+
+func (b *festivalBackend) Name() string    { return BackendFestival }
+func (b *festivalBackend) Available() bool { return hasCommand("festival") }
+
+func (b *festivalBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	var script strings.Builder
+	if voice := b.cfg.voice(BackendFestival, opts.Language, ""); voice != "" {
+		fmt.Fprintf(&script, "(voice_%s)\n", voice)
+	}
+	// Duration_Stretch > 1 放慢、< 1 加快
+	fmt.Fprintf(&script, "(Parameter.set 'Duration_Stretch %.2f)\n", lengthScale(rate(opts)))
+	fmt.Fprintf(&script, "(SayText %s)\n", schemeString(text))
+
+	cmd := exec.CommandContext(ctx, "festival", "--pipe")
+	cmd.Stdin = strings.NewReader(script.String())
+	return cmd.Run()
+}
+
+// schemeString 將文字轉為 Scheme 字串字面值
+func schemeString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// lengthScale 將每分鐘字數換算為時間縮放比例（festival 的 Duration_Stretch、piper 的 length_scale）
+func lengthScale(wpm int) float64 {
+	return float64(DefaultRate) / float64(wpm)
+}
+
+// piperBackend piper 本地神經網路 TTS：合成 wav 後以系統播放器播放。
+// 需在 tts.voices.piper 依語言設定模型路徑。
+type piperBackend struct{ cfg TTSConfig }
+
+func (b *piperBackend) Name() string { return BackendPiper }
+
+func (b *piperBackend) Available() bool {
+	return hasCommand("piper") && audioPlayer() != nil && len(b.cfg.Voices[BackendPiper]) > 0
+}
+
+func (b *piperBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	model := expandHome(b.cfg.voice(BackendPiper, opts.Language, ""))
+	if model == "" {
+		return fmt.Errorf("未設定 %s 語言的 piper 模型", opts.Language)
+	}
+
+	out, err := os.CreateTemp("", "voice-reminder-*.wav")
+	if err != nil {
+		return err
+	}
+	_ = out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.CommandContext(ctx, "piper", "--model", model,
+		"--length_scale", strconv.FormatFloat(lengthScale(rate(opts)), 'f', 2, 64),
+		"--output_file", out.Name())
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("piper 合成失敗: %w", err)
+	}
+
+	player := audioPlayer()
+	if player == nil {
+		return errors.New("找不到音訊播放程式")
+	}
+	return runCommand(ctx, player[0], append(player[1:], out.Name())...)
+}
+
+// expandHome 將開頭的 ~/ 展開為家目錄
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// audioPlayer 回傳第一個可用的 wav 播放指令（不含檔名）
+func audioPlayer() []string {
+	for _, p := range [][]string{{"afplay"}, {"paplay"}, {"aplay", "-q"}, {"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"}} {
+		if hasCommand(p[0]) {
+			return p
+		}
+	}
+	return nil
+}
+
+// commandBackend 使用者自訂的指令樣板。
+// 樣板先以空白切成參數再逐一替換，{text} 即使含空白或引號也是單一參數，不經過 shell。
+type commandBackend struct{ cfg TTSConfig }
+
+func (b *commandBackend) Name() string { return BackendCustom }
+
+func (b *commandBackend) Available() bool {
+	args := strings.Fields(b.cfg.Command)
+	return len(args) > 0 && hasCommand(args[0])
+}
+
+func (b *commandBackend) Speak(ctx context.Context, text string, opts SpeakOptions) error {
+	args := expandCommand(b.cfg.Command, text, opts, b.cfg.voice(BackendCustom, opts.Language, ""))
+	if len(args) == 0 {
+		return errors.New("未設定 tts.command")
+	}
+	return runCommand(ctx, args[0], args[1:]...)
+}
+
+// expandCommand 展開指令樣板的 {text}、{lang}、{rate}、{voice}
+func expandCommand(tmpl, text string, opts SpeakOptions, voice string) []string {
+	r := strings.NewReplacer("{text}", text, "{lang}", opts.Language, "{rate}", strconv.Itoa(rate(opts)), "{voice}", voice)
+	args := strings.Fields(tmpl)
+	for i, a := range args {
+		args[i] = r.Replace(a)
+	}
+	return args
+}
+
+// rate 回傳有效的語速
+func rate(opts SpeakOptions) int {
+	if opts.Rate <= 0 {
+		return DefaultRate
+	}
+	return opts.Rate
+}
+
+// runCommand 執行指令直到結束或 ctx 逾時（逾時時終止行程）
+func runCommand(ctx context.Context, name string, args ...string) error {
+	if err := exec.CommandContext(ctx, name, args...).Run(); err != nil {
+		return fmt.Errorf("命令執行失敗: %v", err)
+	}
+	return nil
+}
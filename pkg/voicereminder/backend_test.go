@@ -0,0 +1,123 @@
+package voicereminder
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBackend 記錄呼叫並回傳指定結果的測試後端
+type fakeBackend struct {
+	name      string
+	available bool
+	err       error
+	hang      bool // 一直等到 context 逾時
+	calls     int
+}
+
+func (b *fakeBackend) Name() string    { return b.name }
+func (b *fakeBackend) Available() bool { return b.available }
+func (b *fakeBackend) Speak(ctx context.Context, _ string, _ SpeakOptions) error {
+	b.calls++
+	if b.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return b.err
+}
+
+func TestSpeakChainFallsBack(t *testing.T) {
+	missing := &fakeBackend{name: "missing"}
+	broken := &fakeBackend{name: "broken", available: true, err: errors.New("boom")}
+	working := &fakeBackend{name: "working", available: true}
+	after := &fakeBackend{name: "after", available: true}
+
+	err := speakChain([]Backend{missing, broken, working, after}, "hi", SpeakOptions{}, time.Second, nil)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if missing.calls != 0 || broken.calls != 1 || working.calls != 1 || after.calls != 0 {
+		t.Errorf("unexpected calls: missing=%d broken=%d working=%d after=%d", missing.calls, broken.calls, working.calls, after.calls)
+	}
+
+	if err := speakChain([]Backend{missing}, "hi", SpeakOptions{}, time.Second, nil); !errors.Is(err, errNoBackend) {
+		t.Errorf("expected errNoBackend, got %v", err)
+	}
+	if err := speakChain([]Backend{broken}, "hi", SpeakOptions{}, time.Second, nil); err == nil || errors.Is(err, errNoBackend) {
+		t.Errorf("expected backend error, got %v", err)
+	}
+}
+
+func TestSpeakChainTimeoutPerBackend(t *testing.T) {
+	stuck := &fakeBackend{name: "stuck", available: true, hang: true}
+	working := &fakeBackend{name: "working", available: true}
+
+	// 卡住的後端超時後，下一個後端有自己的時間可以播放
+	if err := speakChain([]Backend{stuck, working}, "hi", SpeakOptions{}, 20*time.Millisecond, nil); err != nil {
+		t.Fatalf("expected fallback to succeed, got %v", err)
+	}
+	if stuck.calls != 1 || working.calls != 1 {
+		t.Errorf("unexpected calls: stuck=%d working=%d", stuck.calls, working.calls)
+	}
+}
+
+func TestConfigBackends(t *testing.T) {
+	cfg := &Config{TTS: TTSConfig{Backends: []string{"piper", "nope", "spd-say", "custom"}}}
+	var names []string
+	for _, b := range cfg.Backends(nil) {
+		names = append(names, b.Name())
+	}
+	if want := []string{"piper", "spd-say", "custom"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Backends() = %v, want %v", names, want)
+	}
+
+	// 有自訂指令時預設鏈以 custom 開頭
+	cfg = &Config{TTS: TTSConfig{Command: "mytts {text}"}}
+	if chain := cfg.Backends(nil); len(chain) < 2 || chain[0].Name() != BackendCustom {
+		t.Errorf("expected custom first in default chain, got %d backends", len(chain))
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	got := expandCommand("mimic3 --voice {voice} --rate={rate} --lang {lang} {text}",
+		`say "hi"; rm -rf /`, SpeakOptions{Language: "en", Rate: 200}, "en_US/vctk")
+	want := []string{"mimic3", "--voice", "en_US/vctk", "--rate=200", "--lang", "en", `say "hi"; rm -rf /`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandCommand = %q, want %q", got, want)
+	}
+}
+
+func TestRateMapping(t *testing.T) {
+	tests := []struct {
+		wpm  int
+		want int
+	}{
+		{180, 0}, {360, 100}, {90, -50}, {1000, 100}, {0, -100},
+	}
+	for _, tt := range tests {
+		if got := spdRate(tt.wpm); got != tt.want {
+			t.Errorf("spdRate(%d) = %d, want %d", tt.wpm, got, tt.want)
+		}
+	}
+	if got := lengthScale(360); got != 0.5 {
+		t.Errorf("lengthScale(360) = %v, want 0.5", got)
+	}
+	if got := rate(SpeakOptions{}); got != DefaultRate {
+		t.Errorf("rate of zero options = %d, want %d", got, DefaultRate)
+	}
+}
+
+func TestVoiceMapping(t *testing.T) {
+	cfg := TTSConfig{Voices: map[string]map[string]string{"espeak-ng": {"zh": "yue"}}}
+	if got := cfg.voice("espeak-ng", "zh", "cmn"); got != "yue" {
+		t.Errorf("configured voice = %q, want yue", got)
+	}
+	if got := cfg.voice("espeak-ng", "en", "en"); got != "en" {
+		t.Errorf("fallback voice = %q, want en", got)
+	}
+	if got := schemeString(`say "hi" \ now`); got != `"say \"hi\" \\ now"` {
+		t.Errorf("schemeString = %s", got)
+	}
+}
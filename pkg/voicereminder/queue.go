@@ -0,0 +1,233 @@
+package voicereminder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// QueueWorkerFlag 以背景 worker 模式啟動 voice-reminder 的命令列參數
+const QueueWorkerFlag = "--speak-queue"
+
+// DefaultCoalesceWindow 相同訊息在此時間內只播放一次
+const DefaultCoalesceWindow = 3 * time.Second
+
+// maxQueueAge 超過此時間仍未播放的項目直接丟棄（事件已過時）
+const maxQueueAge = time.Minute
+
+// QueueConfig 語音佇列設定
+type QueueConfig struct {
+	// Mode "async"（預設）：hook 將訊息放入佇列後立即返回，由背景 worker 依序播放；
+	// "sync"：在 hook 行程內直接播放（舊行為）
+	Mode string `json:"mode"`
+	// CoalesceSeconds 相同訊息合併的時間窗（秒）；0 使用預設值 3 秒
+	CoalesceSeconds int `json:"coalesce_seconds"`
+}
+
+// Async 是否使用背景佇列；不支援佇列的平台（見 queue_other.go）一律同步播放
+func (q QueueConfig) Async() bool {
+	return queueSupported && q.Mode != "sync"
+}
+
+// Window 相同訊息合併的時間窗
+func (q QueueConfig) Window() time.Duration {
+	if q.CoalesceSeconds > 0 {
+		return time.Duration(q.CoalesceSeconds) * time.Second
+	}
+	return DefaultCoalesceWindow
+}
+
+// Utterance 佇列中的一則語音
+type Utterance struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language"`
+	Rate     int       `json:"rate"`
+	Event    string    `json:"event"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// queueDir 佇列目錄；每則語音一個 JSON 檔，檔名以納秒時間戳排序
+func queueDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".claude", "omystatusline", "plugins", "voice-reminder", "data", "queue"), nil
+}
+
+// Enqueue 將語音放入跨行程佇列。佇列中已有相同訊息，或 window 內剛播放過時略過，回傳 false。
+func Enqueue(u Utterance, window time.Duration) (bool, error) {
+	dir, err := queueDir()
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	if u.QueuedAt.IsZero() {
+		u.QueuedAt = time.Now()
+	}
+
+	if last, ok := loadLastSpoken(dir); ok && last.Text == u.Text && u.QueuedAt.Sub(last.QueuedAt) < window {
+		return false, nil
+	}
+	pending, err := pendingItems(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pending {
+		if p.Text == u.Text {
+			return false, nil
+		}
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return false, err
+	}
+	// 先寫暫存檔再改名，worker 不會讀到寫到一半的檔案
+	name := fmt.Sprintf("%020d-%d.json", u.QueuedAt.UnixNano(), os.Getpid())
+	tmp := filepath.Join(dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// RunQueue 取得佇列鎖後依序播放所有項目直到佇列清空；鎖已被其他 worker 持有時立即返回。
+// 播放前合併 window 內的重複訊息，並丟棄超過 maxQueueAge 的項目。
+func RunQueue(speak func(Utterance) error, window time.Duration, logger *Logger) error {
+	dir, err := queueDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, "queue.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	for {
+		locked, err := tryLock(lock)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			if logger != nil {
+				logger.Log("已有語音 worker 在執行，交由其處理佇列")
+			}
+			return nil
+		}
+		drainQueue(dir, speak, window, logger)
+		unlock(lock)
+
+		// 解鎖前後可能有新項目加入，而其 worker 因取不到鎖已結束；再檢查一次
+		if pending, err := pendingItems(dir); err != nil || len(pending) == 0 {
+			return err
+		}
+	}
+}
+
+// drainQueue 依序播放佇列項目直到清空（呼叫端需持有佇列鎖）
+func drainQueue(dir string, speak func(Utterance) error, window time.Duration, logger *Logger) {
+	last, _ := loadLastSpoken(dir)
+	for {
+		pending, err := pendingItems(dir)
+		if err != nil || len(pending) == 0 {
+			return
+		}
+		item := pending[0]
+		_ = os.Remove(item.path)
+
+		switch {
+		case time.Since(item.QueuedAt) > maxQueueAge:
+			if logger != nil {
+				logger.Log("丟棄過時的語音: %s", item.Text)
+			}
+			continue
+		case item.Text == last.Text && item.QueuedAt.Sub(last.QueuedAt) < window:
+			if logger != nil {
+				logger.Log("合併重複的語音: %s", item.Text)
+			}
+			continue
+		}
+
+		if logger != nil {
+			logger.Log("播放佇列語音 (%s): %s", item.Event, item.Text)
+		}
+		if err := speak(item.Utterance); err != nil && logger != nil {
+			logger.Log("語音播放錯誤: %v", err)
+		}
+		last = item.Utterance
+		saveLastSpoken(dir, last)
+	}
+}
+
+// queueItem 佇列檔案與其內容
+type queueItem struct {
+	Utterance
+	path string
+}
+
+// pendingItems 依加入順序列出佇列項目；無法解析的檔案直接刪除
+func pendingItems(dir string) ([]queueItem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name := e.Name(); !e.IsDir() && strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, ".") && name != lastSpokenFile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var items []queueItem
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // 已被其他 worker 取走
+		}
+		var u Utterance
+		if err := json.Unmarshal(data, &u); err != nil {
+			_ = os.Remove(path)
+			continue
+		}
+		items = append(items, queueItem{Utterance: u, path: path})
+	}
+	return items, nil
+}
+
+// lastSpokenFile 記錄最後播放的語音，供跨行程合併重複訊息
+const lastSpokenFile = "last-spoken.json"
+
+func loadLastSpoken(dir string) (Utterance, bool) {
+	var u Utterance
+	data, err := os.ReadFile(filepath.Join(dir, lastSpokenFile))
+	if err != nil {
+		return u, false
+	}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return Utterance{}, false
+	}
+	return u, true
+}
+
+func saveLastSpoken(dir string, u Utterance) {
+	// 以播放完成的時間為準，避免長語音播完後立刻重播相同內容
+	u.QueuedAt = time.Now()
+	data, err := json.Marshal(u)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, lastSpokenFile), data, 0644)
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package voicereminder
+
+import (
+	"errors"
+	"os"
+)
+
+// queueSupported 此平台沒有 flock 與 setsid，QueueConfig.Async 回傳 false，hook 改為同步播放
+const queueSupported = false
+
+// errQueueUnsupported 此平台不支援背景語音佇列
+var errQueueUnsupported = errors.New("speech queue is not supported on this platform")
+
+// StartWorker 此平台不支援背景 worker
+func StartWorker() error {
+	return errQueueUnsupported
+}
+
+// tryLock 此平台不支援佇列鎖
+func tryLock(*os.File) (bool, error) {
+	return false, errQueueUnsupported
+}
+
+// unlock 此平台不支援佇列鎖
+func unlock(*os.File) {}
@@ -0,0 +1,108 @@
+//go:build unix
+
+package voicereminder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnqueueCoalescesPending(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if ok, err := Enqueue(Utterance{Text: "done"}, time.Second); err != nil || !ok {
+		t.Fatalf("first enqueue: ok=%v err=%v", ok, err)
+	}
+	if ok, err := Enqueue(Utterance{Text: "done"}, time.Second); err != nil || ok {
+		t.Errorf("duplicate should be coalesced: ok=%v err=%v", ok, err)
+	}
+	if ok, err := Enqueue(Utterance{Text: "other"}, time.Second); err != nil || !ok {
+		t.Errorf("different message should be queued: ok=%v err=%v", ok, err)
+	}
+
+	dir, _ := queueDir()
+	items, err := pendingItems(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 pending items, got %d", len(items))
+	}
+}
+
+func TestRunQueueSpeaksInOrderAndCoalesces(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Now()
+	for i, text := range []string{"first", "second", "third"} {
+		if _, err := Enqueue(Utterance{Text: text, QueuedAt: now.Add(time.Duration(i) * time.Millisecond)}, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 過時的項目不播放
+	if _, err := Enqueue(Utterance{Text: "stale", QueuedAt: now.Add(-2 * maxQueueAge)}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var spoken []string
+	speak := func(u Utterance) error {
+		spoken = append(spoken, u.Text)
+		return nil
+	}
+	if err := RunQueue(speak, time.Second, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(spoken, want) {
+		t.Errorf("spoken = %v, want %v", spoken, want)
+	}
+
+	// 剛播放過的訊息在時間窗內不再加入佇列
+	if ok, _ := Enqueue(Utterance{Text: "third"}, time.Minute); ok {
+		t.Error("message spoken within the window should be coalesced")
+	}
+	if ok, _ := Enqueue(Utterance{Text: "third"}, 0); !ok {
+		t.Error("message should be queued again outside the window")
+	}
+}
+
+func TestRunQueueSkipsWhenLocked(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Enqueue(Utterance{Text: "hello"}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	dir, _ := queueDir()
+	lock, err := os.OpenFile(filepath.Join(dir, "queue.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+	if locked, err := tryLock(lock); err != nil || !locked {
+		t.Fatalf("tryLock: locked=%v err=%v", locked, err)
+	}
+
+	called := false
+	if err := RunQueue(func(Utterance) error { called = true; return nil }, time.Second, nil); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("worker should not speak while another worker holds the lock")
+	}
+	if items, _ := pendingItems(dir); len(items) != 1 {
+		t.Errorf("queue should be left for the lock holder, got %d items", len(items))
+	}
+}
+
+func TestQueueConfigDefaults(t *testing.T) {
+	var q QueueConfig
+	if !q.Async() || q.Window() != DefaultCoalesceWindow {
+		t.Errorf("zero config should be async with default window, got async=%v window=%v", q.Async(), q.Window())
+	}
+	q = QueueConfig{Mode: "sync", CoalesceSeconds: 5}
+	if q.Async() || q.Window() != 5*time.Second {
+		t.Errorf("unexpected config: async=%v window=%v", q.Async(), q.Window())
+	}
+}
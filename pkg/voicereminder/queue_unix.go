@@ -0,0 +1,45 @@
+//go:build unix
+
+package voicereminder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// queueSupported 此平台支援背景語音佇列
+const queueSupported = true
+
+// StartWorker 以分離的背景行程啟動佇列 worker（voice-reminder --speak-queue），不等待其結束。
+// 已有 worker 在執行時，新的 worker 取不到鎖會立即結束，佇列由既有的 worker 處理。
+func StartWorker() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, QueueWorkerFlag)
+	// 脫離 hook 的行程群組與 stdio，hook 結束時 Claude Code 不必等待播放完成
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// tryLock 以非阻塞方式取得佇列的獨占鎖；已被其他行程持有時回傳 false
+func tryLock(f *os.File) (bool, error) {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// unlock 釋放佇列鎖
+func unlock(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
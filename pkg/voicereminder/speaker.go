@@ -1,6 +1,7 @@
 package voicereminder
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"runtime"
@@ -21,8 +22,13 @@ func Speak(message string, speed int, language string) error {
 	return SpeakWithLogger(message, speed, language, nil)
 }
 
-// SpeakWithLogger 播放語音，支援 logger（帶超時和重試機制）
+// SpeakWithLogger 以預設後端鏈播放語音，支援 logger（帶超時和重試機制）
 func SpeakWithLogger(message string, speed int, language string, logger *Logger) error {
+	return SpeakWith(DefaultBackends(), message, SpeakOptions{Language: language, Rate: speed}, logger)
+}
+
+// SpeakWith 依序嘗試後端鏈播放語音（帶超時和重試機制），全部失敗時播放降級音效
+func SpeakWith(chain []Backend, message string, opts SpeakOptions, logger *Logger) error {
 	// 重試邏輯
 	for attempt := 0; attempt <= MaxRetries; attempt++ {
 		if attempt > 0 {
@@ -33,7 +39,7 @@ func SpeakWithLogger(message string, speed int, language string, logger *Logger)
 		}
 
 		// 嘗試播放語音
-		err := speakOnce(message, speed, language, logger)
+		err := speakOnce(chain, message, opts, logger)
 		if err == nil {
 			return nil
 		}
@@ -41,6 +47,10 @@ func SpeakWithLogger(message string, speed int, language string, logger *Logger)
 		if logger != nil {
 			logger.Log("播放失敗: %v", err)
 		}
+		// 沒有可用的後端時重試也沒有意義
+		if errors.Is(err, errNoBackend) {
+			break
+		}
 	}
 
 	// 所有重試都失敗，使用降級音效
@@ -50,59 +60,9 @@ func SpeakWithLogger(message string, speed int, language string, logger *Logger)
 	return playFallbackSound()
 }
 
-// speakOnce 執行一次語音播放（帶超時機制）
-func speakOnce(message string, speed int, language string, logger *Logger) error {
-	if runtime.GOOS == "darwin" {
-		return speakMacOS(message, speed, language, logger)
-	}
-
-	// Linux 嘗試使用 espeak
-	if hasCommand("espeak") {
-		return speakWithTimeout("espeak", []string{message}, logger)
-	}
-
-	// 降級：播放系統音效
-	return playFallbackSound()
-}
-
-// speakMacOS 在 macOS 上播放語音（帶超時機制）
-func speakMacOS(message string, speed int, language string, logger *Logger) error {
-	voice := selectVoice(language)
-	args := []string{"-v", voice, "-r", fmt.Sprintf("%d", speed), message}
-	return speakWithTimeout("say", args, logger)
-}
-
-// speakWithTimeout 執行語音命令並帶超時機制
-func speakWithTimeout(command string, args []string, logger *Logger) error {
-	cmd := exec.Command(command, args...)
-
-	// 建立 channel 來接收執行結果
-	done := make(chan error, 1)
-
-	// 在 goroutine 中執行命令
-	go func() {
-		done <- cmd.Run()
-	}()
-
-	// 等待命令完成或超時
-	select {
-	case err := <-done:
-		// 命令正常完成
-		if err != nil {
-			return fmt.Errorf("命令執行失敗: %v", err)
-		}
-		return nil
-
-	case <-time.After(SpeakTimeout):
-		// 超時，嘗試終止進程
-		if cmd.Process != nil {
-			_ = cmd.Process.Kill()
-		}
-		if logger != nil {
-			logger.Log("⚠️ 語音播放超時 (%.0f 秒)，已終止進程", SpeakTimeout.Seconds())
-		}
-		return fmt.Errorf("語音播放超時")
-	}
+// speakOnce 以後端鏈執行一次語音播放（每個後端各自 SpeakTimeout 超時）
+func speakOnce(chain []Backend, message string, opts SpeakOptions, logger *Logger) error {
+	return speakChain(chain, message, opts, SpeakTimeout, logger)
 }
 
 // selectVoice 根據語言選擇合適的語音
@@ -11,6 +11,8 @@ type Config struct {
 	MessagesEN        map[string]EventMessages `json:"messages_en"`
 	SoundEffects      SoundConfig              `json:"sound_effects"`
 	PreToolUseFilters PreToolUseFilters        `json:"pre_tool_use_filters"`
+	TTS               TTSConfig                `json:"tts"`
+	Queue             QueueConfig              `json:"queue"`
 }
 
 // EventMessages 事件訊息配置